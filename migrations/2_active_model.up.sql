@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN active_model_id BIGINT REFERENCES models(id) ON DELETE SET NULL;
@@ -0,0 +1,5 @@
+CREATE TABLE chat_settings (
+    chat_id BIGINT PRIMARY KEY,
+    admin_only BOOLEAN NOT NULL DEFAULT FALSE,
+    model_id BIGINT REFERENCES models(id) ON DELETE SET NULL
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS audiobook_chapters;
+DROP TABLE IF EXISTS audiobooks;
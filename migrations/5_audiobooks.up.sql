@@ -0,0 +1,23 @@
+CREATE TABLE audiobooks (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chat_id BIGINT NOT NULL,
+    model_id BIGINT NOT NULL REFERENCES models(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    lang TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'processing',
+    message_id INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX audiobooks_status_idx ON audiobooks (status);
+
+CREATE TABLE audiobook_chapters (
+    book_id BIGINT NOT NULL REFERENCES audiobooks(id) ON DELETE CASCADE,
+    idx INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    text TEXT NOT NULL,
+    done BOOLEAN NOT NULL DEFAULT FALSE,
+
+    PRIMARY KEY (book_id, idx)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS history_days;
+DROP TABLE IF EXISTS generations;
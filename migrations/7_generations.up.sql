@@ -0,0 +1,16 @@
+CREATE TABLE generations (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    model_id BIGINT REFERENCES models(id) ON DELETE SET NULL,
+    model_name TEXT NOT NULL,
+    text TEXT NOT NULL,
+    params JSONB NOT NULL DEFAULT '{}',
+    duration INTEGER NOT NULL DEFAULT 0,
+    file_id TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX generations_user_created_idx ON generations (user_id, created_at DESC);
+
+ALTER TABLE users
+    ADD COLUMN history_days INTEGER;
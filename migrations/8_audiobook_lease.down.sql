@@ -0,0 +1,3 @@
+ALTER TABLE audiobooks
+    DROP COLUMN IF EXISTS lease_until,
+    DROP COLUMN IF EXISTS owner;
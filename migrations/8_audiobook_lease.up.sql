@@ -0,0 +1,3 @@
+ALTER TABLE audiobooks
+    ADD COLUMN owner TEXT,
+    ADD COLUMN lease_until TIMESTAMP;
@@ -0,0 +1,2 @@
+ALTER TABLE generations
+    ADD COLUMN hidden BOOLEAN NOT NULL DEFAULT FALSE;
@@ -39,6 +39,11 @@ class AudioProcessorStub(object):
                 request_serializer=audio__processor__pb2.ContentRequest.SerializeToString,
                 response_deserializer=audio__processor__pb2.ProcessingResponse.FromString,
                 _registered_method=True)
+        self.Transcribe = channel.unary_unary(
+                '/audio_processing.v1.AudioProcessor/Transcribe',
+                request_serializer=audio__processor__pb2.TranscribeRequest.SerializeToString,
+                response_deserializer=audio__processor__pb2.TranscribeResponse.FromString,
+                _registered_method=True)
 
 
 class AudioProcessorServicer(object):
@@ -50,6 +55,12 @@ class AudioProcessorServicer(object):
         context.set_details('Method not implemented!')
         raise NotImplementedError('Method not implemented!')
 
+    def Transcribe(self, request, context):
+        """Missing associated documentation comment in .proto file."""
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
 
 def add_AudioProcessorServicer_to_server(servicer, server):
     rpc_method_handlers = {
@@ -58,6 +69,11 @@ def add_AudioProcessorServicer_to_server(servicer, server):
                     request_deserializer=audio__processor__pb2.ContentRequest.FromString,
                     response_serializer=audio__processor__pb2.ProcessingResponse.SerializeToString,
             ),
+            'Transcribe': grpc.unary_unary_rpc_method_handler(
+                    servicer.Transcribe,
+                    request_deserializer=audio__processor__pb2.TranscribeRequest.FromString,
+                    response_serializer=audio__processor__pb2.TranscribeResponse.SerializeToString,
+            ),
     }
     generic_handler = grpc.method_handlers_generic_handler(
             'audio_processing.v1.AudioProcessor', rpc_method_handlers)
@@ -95,3 +111,30 @@ class AudioProcessor(object):
             timeout,
             metadata,
             _registered_method=True)
+
+    @staticmethod
+    def Transcribe(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_unary(
+            request,
+            target,
+            '/audio_processing.v1.AudioProcessor/Transcribe',
+            audio__processor__pb2.TranscribeRequest.SerializeToString,
+            audio__processor__pb2.TranscribeResponse.FromString,
+            options,
+            channel_credentials,
+            insecure,
+            call_credentials,
+            compression,
+            wait_for_ready,
+            timeout,
+            metadata,
+            _registered_method=True)
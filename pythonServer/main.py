@@ -1,7 +1,13 @@
 import grpc
+import hmac
 from concurrent import futures
 import torch
 import os
+import signal
+import tempfile
+import time
+import whisper
+from grpc_health.v1 import health, health_pb2, health_pb2_grpc
 from TTS.api import TTS
 import audio_processor_pb2
 import audio_processor_pb2_grpc
@@ -14,6 +20,7 @@ class AudioProcessorServicer(audio_processor_pb2_grpc.AudioProcessorServicer):
         self.device = "cuda" if torch.cuda.is_available() else "cpu"
         self.tts = TTS(self.model_name).to(self.device)
         self.user_voice_sample = "1.wav"
+        self.whisper = whisper.load_model(os.environ.get("WHISPER_MODEL", "small"), device=self.device)
 
     def ProcessContent(self, request, context):
         try:
@@ -27,6 +34,7 @@ class AudioProcessorServicer(audio_processor_pb2_grpc.AudioProcessorServicer):
                 language="ru",
                 speaker_wav=input_audio_path,
                 file_path=output_audio_path,
+                speed=request.speed or 1.0,
             )
 
             with open(output_audio_path, "rb") as f:
@@ -43,14 +51,170 @@ class AudioProcessorServicer(audio_processor_pb2_grpc.AudioProcessorServicer):
                 result=audio_processor_pb2.AudioResult()
             )
 
+    def Transcribe(self, request, context):
+        try:
+            with tempfile.NamedTemporaryFile(suffix=".ogg") as f:
+                f.write(request.audio.data)
+                f.flush()
+                result = self.whisper.transcribe(
+                    f.name,
+                    language=request.language or None,
+                    fp16=self.device == "cuda",
+                )
+
+            return audio_processor_pb2.TranscribeResponse(
+                status="OK",
+                text=result["text"].strip(),
+            )
+
+        except Exception as e:
+            return audio_processor_pb2.TranscribeResponse(
+                status=f"ERROR: {str(e)}",
+            )
+
+SERVICE_NAME = audio_processor_pb2.DESCRIPTOR.services_by_name["AudioProcessor"].full_name
+
+# При остановке сервер сначала сообщает NOT_SERVING, чтобы балансировщик
+# бота перестал присылать запросы, и дорабатывает начатые запросы.
+DRAIN_DELAY = int(os.environ.get("DRAIN_DELAY", "10"))
+DRAIN_TIMEOUT = int(os.environ.get("DRAIN_TIMEOUT", "300"))
+
+# Защита канала. TLS_CERT и TLS_KEY включают TLS, TLS_CLIENT_CA
+# дополнительно требует сертификат клиента (mTLS), AUTH_TOKEN — токен
+# в заголовке authorization каждого запроса.
+TLS_CERT = os.environ.get("TLS_CERT", "")
+TLS_KEY = os.environ.get("TLS_KEY", "")
+TLS_CLIENT_CA = os.environ.get("TLS_CLIENT_CA", "")
+AUTH_TOKEN = os.environ.get("AUTH_TOKEN", "")
+
+class TokenInterceptor(grpc.ServerInterceptor):
+    def __init__(self, token):
+        self.expected = f"Bearer {token}".encode()
+
+        def deny(request, context):
+            context.abort(grpc.StatusCode.UNAUTHENTICATED, "неверный токен")
+
+        self.deny = grpc.unary_unary_rpc_method_handler(deny)
+
+    def intercept_service(self, continuation, handler_call_details):
+        metadata = dict(handler_call_details.invocation_metadata or ())
+        token = metadata.get("authorization", "").encode()
+        if not hmac.compare_digest(token, self.expected):
+            return self.deny
+        return continuation(handler_call_details)
+
+# ID запроса от бота. Сервер пишет его в логи и возвращает в
+# заголовках ответа, чтобы запрос можно было найти в логах обеих сторон.
+REQUEST_ID_HEADER = "x-request-id"
+
+class RequestLogInterceptor(grpc.ServerInterceptor):
+    def intercept_service(self, continuation, handler_call_details):
+        handler = continuation(handler_call_details)
+        method = handler_call_details.method
+        if handler is None or handler.unary_unary is None or method.startswith("/grpc.health."):
+            return handler
+
+        metadata = dict(handler_call_details.invocation_metadata or ())
+        request_id = metadata.get(REQUEST_ID_HEADER, "-")
+        behavior = handler.unary_unary
+
+        def logged(request, context):
+            context.send_initial_metadata(((REQUEST_ID_HEADER, request_id),))
+            start = time.monotonic()
+            status = "EXCEPTION"
+            try:
+                response = behavior(request, context)
+                status = getattr(response, "status", "OK")
+                return response
+            finally:
+                print(f"[{request_id}] {method} {status} {time.monotonic() - start:.2f}s", flush=True)
+
+        return grpc.unary_unary_rpc_method_handler(
+            logged,
+            request_deserializer=handler.request_deserializer,
+            response_serializer=handler.response_serializer,
+        )
+
+# Трейсинг: спаны запросов продолжают трейс бота из заголовка
+# traceparent. TRACING_EXPORTER=otlp отправляет их коллектору из
+# OTEL_EXPORTER_OTLP_ENDPOINT, по умолчанию трейсинг выключен.
+TRACING_EXPORTER = os.environ.get("TRACING_EXPORTER") or "none"
+TRACING_SAMPLE_RATIO = float(os.environ.get("TRACING_SAMPLE_RATIO") or "1")
+
+def tracing_interceptor():
+    if TRACING_EXPORTER == "none":
+        return None
+
+    from opentelemetry import trace
+    from opentelemetry.instrumentation.grpc import filters, server_interceptor
+    from opentelemetry.sdk.resources import Resource
+    from opentelemetry.sdk.trace import TracerProvider
+    from opentelemetry.sdk.trace.export import BatchSpanProcessor, ConsoleSpanExporter
+    from opentelemetry.sdk.trace.sampling import ParentBased, TraceIdRatioBased
+
+    if TRACING_EXPORTER == "otlp":
+        from opentelemetry.exporter.otlp.proto.grpc.trace_exporter import OTLPSpanExporter
+        exporter = OTLPSpanExporter()
+    elif TRACING_EXPORTER == "stdout":
+        exporter = ConsoleSpanExporter()
+    else:
+        raise RuntimeError(f"неизвестный экспортёр трейсов {TRACING_EXPORTER!r}")
+
+    provider = TracerProvider(
+        resource=Resource.create({"service.name": "audio-processor"}),
+        sampler=ParentBased(TraceIdRatioBased(TRACING_SAMPLE_RATIO)),
+    )
+    provider.add_span_processor(BatchSpanProcessor(exporter))
+    trace.set_tracer_provider(provider)
+    return server_interceptor(filter_=filters.negate(filters.health_check()))
+
+def read_file(path):
+    with open(path, "rb") as f:
+        return f.read()
+
+def add_port(server, address):
+    if not TLS_CERT:
+        if AUTH_TOKEN or TLS_CLIENT_CA:
+            raise RuntimeError("AUTH_TOKEN и TLS_CLIENT_CA требуют TLS_CERT и TLS_KEY")
+        print("ВНИМАНИЕ: канал не защищён, задайте TLS_CERT и TLS_KEY")
+        server.add_insecure_port(address)
+        return
+    credentials = grpc.ssl_server_credentials(
+        [(read_file(TLS_KEY), read_file(TLS_CERT))],
+        root_certificates=read_file(TLS_CLIENT_CA) if TLS_CLIENT_CA else None,
+        require_client_auth=bool(TLS_CLIENT_CA),
+    )
+    server.add_secure_port(address, credentials)
+
 def serve():
-    server = grpc.server(futures.ThreadPoolExecutor(max_workers=10))
+    interceptors = [RequestLogInterceptor()]
+    if AUTH_TOKEN:
+        interceptors.insert(0, TokenInterceptor(AUTH_TOKEN))
+    tracing = tracing_interceptor()
+    if tracing:
+        interceptors.insert(0, tracing)
+    server = grpc.server(futures.ThreadPoolExecutor(max_workers=10), interceptors=interceptors)
     audio_processor_pb2_grpc.add_AudioProcessorServicer_to_server(
         AudioProcessorServicer(), server
     )
-    server.add_insecure_port("[::]:50051")
+    health_servicer = health.HealthServicer()
+    health_pb2_grpc.add_HealthServicer_to_server(health_servicer, server)
+    add_port(server, "[::]:50051")
     server.start()
+    for service in (SERVICE_NAME, ""):
+        health_servicer.set(service, health_pb2.HealthCheckResponse.SERVING)
     print("gRPC сервер запущен на порту 50051")
+
+    def drain(signum, frame):
+        print("Остановка: новые запросы не принимаются, начатые дорабатываются")
+        health_servicer.enter_graceful_shutdown()
+        time.sleep(DRAIN_DELAY)
+        server.stop(DRAIN_TIMEOUT).wait()
+        if tracing:
+            from opentelemetry import trace
+            trace.get_tracer_provider().shutdown()
+
+    signal.signal(signal.SIGTERM, drain)
     server.wait_for_termination()
 
 if __name__ == "__main__":
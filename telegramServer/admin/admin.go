@@ -0,0 +1,209 @@
+// Package admin — служебный HTTP-сервер бота: проверки живости и
+// готовности для Docker и оркестраторов, метрики Prometheus, профили
+// pprof и управление серверами AudioProcessor.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+	"kursach/client"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+	"time"
+)
+
+const checkTimeout = 3 * time.Second
+
+// Check — проверка зависимости для /readyz.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Backends управляет серверами AudioProcessor.
+type Backends interface {
+	Backends() []client.BackendStatus
+	Drain(ctx context.Context, address string) error
+	Resume(address string) error
+}
+
+type Options struct {
+	Listen string
+	// Token открывает доступ к pprof и /admin/. Пустой токен отключает
+	// эти разделы.
+	Token string
+
+	// Live и Ready сообщают состояние приложения: неготовое приложение
+	// не проверяет зависимости.
+	Live   func() bool
+	Ready  func() bool
+	Checks []Check
+
+	Backends Backends
+}
+
+type Server struct {
+	opts     Options
+	listener net.Listener
+	server   *http.Server
+	log      *zap.Logger
+}
+
+// New занимает порт сразу, чтобы ошибка обнаружилась при запуске.
+func New(opts Options, logger *zap.Logger) (*Server, error) {
+	listener, err := net.Listen("tcp", opts.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия порта служебного сервера: %w", err)
+	}
+
+	s := &Server{opts: opts, listener: listener, log: logger}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.healthz)
+	mux.HandleFunc("GET /readyz", s.readyz)
+	mux.Handle("GET /metrics", promhttp.Handler())
+
+	mux.Handle("/debug/pprof/", s.authorized(http.HandlerFunc(pprof.Index)))
+	mux.Handle("/debug/pprof/cmdline", s.authorized(http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("/debug/pprof/profile", s.authorized(http.HandlerFunc(pprof.Profile)))
+	mux.Handle("/debug/pprof/symbol", s.authorized(http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("/debug/pprof/trace", s.authorized(http.HandlerFunc(pprof.Trace)))
+
+	mux.Handle("GET /admin/backends", s.authorized(http.HandlerFunc(s.backends)))
+	mux.Handle("POST /admin/backends/drain", s.authorized(http.HandlerFunc(s.drain)))
+	mux.Handle("POST /admin/backends/resume", s.authorized(http.HandlerFunc(s.resume)))
+
+	s.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s, nil
+}
+
+func (s *Server) Start() {
+	go func() {
+		err := s.server.Serve(s.listener)
+		if !errors.Is(err, http.ErrServerClosed) {
+			s.log.Error("Служебный сервер остановлен с ошибкой", zap.Error(err))
+		}
+	}()
+	s.log.Info("Служебный сервер запущен", zap.String("addr", s.listener.Addr().String()), zap.Bool("admin", s.opts.Token != ""))
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// healthz отвечает, пока процесс работает. Зависимости не проверяются:
+// их недоступность не лечится перезапуском бота.
+func (s *Server) healthz(w http.ResponseWriter, _ *http.Request) {
+	if !s.opts.Live() {
+		http.Error(w, "stopped", http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// readyz проверяет зависимости параллельно, каждую с таймаутом
+// checkTimeout.
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	if !s.opts.Ready() {
+		writeJSON(w, http.StatusServiceUnavailable, readiness{Status: "not ready"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	result := readiness{Status: "ok", Checks: make(map[string]string, len(s.opts.Checks))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, check := range s.opts.Checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := check.Run(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				result.Status = "fail"
+				result.Checks[check.Name] = err.Error()
+				return
+			}
+			result.Checks[check.Name] = "ok"
+		}()
+	}
+	wg.Wait()
+
+	code := http.StatusOK
+	if result.Status != "ok" {
+		code = http.StatusServiceUnavailable
+		s.log.Warn("Проверка готовности не пройдена", zap.Any("checks", result.Checks))
+	}
+	writeJSON(w, code, result)
+}
+
+func (s *Server) backends(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.opts.Backends.Backends())
+}
+
+// drain выводит сервер AudioProcessor из балансировки и отвечает,
+// когда его запросы завершены. Адрес — в параметре address.
+func (s *Server) drain(w http.ResponseWriter, r *http.Request) {
+	address := r.URL.Query().Get("address")
+	if err := s.opts.Backends.Drain(r.Context(), address); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.log.Info("Сервер выведен из балансировки через служебный сервер", zap.String("address", address))
+	writeJSON(w, http.StatusOK, s.opts.Backends.Backends())
+}
+
+func (s *Server) resume(w http.ResponseWriter, r *http.Request) {
+	address := r.URL.Query().Get("address")
+	if err := s.opts.Backends.Resume(address); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.log.Info("Сервер возвращён в балансировку через служебный сервер", zap.String("address", address))
+	writeJSON(w, http.StatusOK, s.opts.Backends.Backends())
+}
+
+// authorized пропускает запросы с заголовком "Authorization: Bearer
+// <token>".
+func (s *Server) authorized(next http.Handler) http.Handler {
+	expected := []byte("Bearer " + s.opts.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.opts.Token == "" {
+			http.NotFound(w, r)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			s.log.Warn("Запрос к служебному серверу без доступа", zap.String("path", r.URL.Path), zap.String("remote", r.RemoteAddr))
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,176 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"kursach/client"
+
+	"go.uber.org/zap"
+)
+
+type fakeBackends struct {
+	drained []string
+}
+
+func (f *fakeBackends) Backends() []client.BackendStatus {
+	return []client.BackendStatus{{Address: "tts:50051", Healthy: true}}
+}
+
+func (f *fakeBackends) Drain(_ context.Context, address string) error {
+	f.drained = append(f.drained, address)
+	return nil
+}
+
+func (f *fakeBackends) Resume(string) error { return nil }
+
+func newTestServer(t *testing.T, opts Options) *Server {
+	t.Helper()
+
+	opts.Listen = "127.0.0.1:0"
+	if opts.Live == nil {
+		opts.Live = func() bool { return true }
+	}
+	if opts.Ready == nil {
+		opts.Ready = func() bool { return true }
+	}
+	if opts.Backends == nil {
+		opts.Backends = &fakeBackends{}
+	}
+	s, err := New(opts, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.listener.Close() })
+	return s
+}
+
+func serve(s *Server, method, path, auth string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	if auth != "" {
+		r.Header.Set("Authorization", auth)
+	}
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestHealthz(t *testing.T) {
+	live := true
+	s := newTestServer(t, Options{Live: func() bool { return live }})
+
+	if w := serve(s, http.MethodGet, "/healthz", ""); w.Code != http.StatusOK {
+		t.Errorf("работающее приложение: %d", w.Code)
+	}
+	live = false
+	if w := serve(s, http.MethodGet, "/healthz", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("остановленное приложение: %d", w.Code)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	ok := Check{Name: "postgres", Run: func(context.Context) error { return nil }}
+	fail := Check{Name: "telegram", Run: func(context.Context) error { return errors.New("нет сети") }}
+
+	tests := []struct {
+		name   string
+		ready  bool
+		checks []Check
+		code   int
+		result readiness
+	}{
+		{
+			name:   "всё исправно",
+			ready:  true,
+			checks: []Check{ok},
+			code:   http.StatusOK,
+			result: readiness{Status: "ok", Checks: map[string]string{"postgres": "ok"}},
+		},
+		{
+			name:   "проверка не пройдена",
+			ready:  true,
+			checks: []Check{ok, fail},
+			code:   http.StatusServiceUnavailable,
+			result: readiness{Status: "fail", Checks: map[string]string{"postgres": "ok", "telegram": "нет сети"}},
+		},
+		{
+			name:   "приложение не готово",
+			checks: []Check{ok},
+			code:   http.StatusServiceUnavailable,
+			result: readiness{Status: "not ready"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, Options{Ready: func() bool { return tt.ready }, Checks: tt.checks})
+
+			w := serve(s, http.MethodGet, "/readyz", "")
+			if w.Code != tt.code {
+				t.Errorf("код %d, ожидался %d", w.Code, tt.code)
+			}
+			var got readiness
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tt.result.Status || len(got.Checks) != len(tt.result.Checks) {
+				t.Fatalf("ответ %+v, ожидался %+v", got, tt.result)
+			}
+			for name, want := range tt.result.Checks {
+				if got.Checks[name] != want {
+					t.Errorf("проверка %s: %q, ожидалось %q", name, got.Checks[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthorized(t *testing.T) {
+	paths := []struct {
+		method, path string
+	}{
+		{http.MethodGet, "/debug/pprof/"},
+		{http.MethodGet, "/debug/pprof/cmdline"},
+		{http.MethodGet, "/admin/backends"},
+		{http.MethodPost, "/admin/backends/drain?address=tts:50051"},
+		{http.MethodPost, "/admin/backends/resume?address=tts:50051"},
+	}
+	tests := []struct {
+		name  string
+		token string
+		auth  string
+		code  int
+	}{
+		{name: "без токена в настройках", auth: "Bearer ", code: http.StatusNotFound},
+		{name: "без заголовка", token: "secret", code: http.StatusUnauthorized},
+		{name: "неверный токен", token: "secret", auth: "Bearer wrong", code: http.StatusUnauthorized},
+		{name: "без схемы Bearer", token: "secret", auth: "secret", code: http.StatusUnauthorized},
+		{name: "верный токен", token: "secret", auth: "Bearer secret", code: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backends := &fakeBackends{}
+			s := newTestServer(t, Options{Token: tt.token, Backends: backends})
+
+			for _, p := range paths {
+				if w := serve(s, p.method, p.path, tt.auth); w.Code != tt.code {
+					t.Errorf("%s %s: код %d, ожидался %d", p.method, p.path, w.Code, tt.code)
+				}
+			}
+			if tt.code != http.StatusOK && len(backends.drained) != 0 {
+				t.Errorf("сервер выведен из балансировки без доступа: %v", backends.drained)
+			}
+		})
+	}
+}
+
+func TestMetricsWithoutToken(t *testing.T) {
+	s := newTestServer(t, Options{Token: "secret"})
+
+	if w := serve(s, http.MethodGet, "/metrics", ""); w.Code != http.StatusOK {
+		t.Errorf("код %d, ожидался %d", w.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"kursach/admin"
+	"kursach/config"
+)
+
+// newAdminServer создаёт служебный сервер. /readyz проверяет базу
+// данных, серверы AudioProcessor и доступ к Bot API.
+func (a *App) newAdminServer(cfg config.Config) (*admin.Server, error) {
+	return admin.New(admin.Options{
+		Listen: cfg.AdminListen,
+		Token:  cfg.AdminToken,
+		Live:   a.Live,
+		Ready:  a.Ready,
+		Checks: []admin.Check{
+			{Name: "postgres", Run: a.dbPool.Ping},
+			{Name: "audio_processor", Run: a.checkAudioProcessor},
+			{Name: "telegram", Run: a.checkTelegram},
+		},
+		Backends: a.audioClient,
+	}, a.log)
+}
+
+func (a *App) checkAudioProcessor(context.Context) error {
+	for _, b := range a.audioClient.Backends() {
+		if b.Healthy && !b.Draining {
+			return nil
+		}
+	}
+	return errors.New("нет доступных серверов AudioProcessor")
+}
+
+// checkTelegram вызывает getMe. Bot API не принимает контекст, поэтому
+// запрос выполняется в фоне, а проверка ограничена дедлайном ctx.
+func (a *App) checkTelegram(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := a.Bot.Raw("getMe", nil)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"kursach/client"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"gopkg.in/telebot.v3"
+)
+
+func TestCheckTelegram(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		hang    bool
+		wantErr error
+	}{
+		{
+			name: "getMe отвечает",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"bot"}}`))
+			},
+		},
+		{
+			name: "неверный токен",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"ok":false,"error_code":401,"description":"Unauthorized"}`))
+			},
+			wantErr: telebot.ErrUnauthorized,
+		},
+		{
+			name:    "Bot API не отвечает",
+			hang:    true,
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.hang {
+					<-release
+					return
+				}
+				tt.handler(w, r)
+			}))
+			defer srv.Close()
+			// Отпускает зависший запрос, иначе Close его ждёт.
+			defer close(release)
+
+			bot, err := telebot.NewBot(telebot.Settings{URL: srv.URL, Token: "token", Offline: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := &App{Bot: bot}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			err = a.checkTelegram(ctx)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ошибка %v, ожидалась %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckAudioProcessor(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	const address = "passthrough:///bufnet"
+	audioClient, err := client.NewAudioProcessorClient(
+		[]client.Endpoint{{Address: address, MaxConcurrent: 1}},
+		client.Security{},
+		zap.NewNop(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(audioClient.Close)
+	a := &App{audioClient: audioClient}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for a.checkAudioProcessor(context.Background()) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("сервер не стал исправным")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Выведенный из балансировки сервер не считается доступным.
+	if err := audioClient.Drain(context.Background(), address); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.checkAudioProcessor(context.Background()); err == nil {
+		t.Error("проверка пройдена без доступных серверов")
+	}
+}
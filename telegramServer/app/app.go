@@ -6,37 +6,85 @@ import (
 	"github.com/jackc/pgx/v4/pgxpool"
 	"go.uber.org/zap"
 	"gopkg.in/telebot.v3"
+	"kursach/admin"
+	"kursach/callback"
 	"kursach/client"
 	"kursach/config"
+	"kursach/defs"
 	"kursach/handler"
+	"kursach/i18n"
+	"kursach/queue"
 	"kursach/service"
 	"kursach/storage"
+	"kursach/tracing"
+	"slices"
+	"sync/atomic"
 	"time"
 )
 
 type App struct {
-	Bot     *telebot.Bot
-	Handler *handler.Handler
-	dbPool  *pgxpool.Pool
-	log     *zap.Logger
+	Bot         *telebot.Bot
+	Handler     *handler.Handler
+	callbacks   *callback.Router
+	queue       *queue.Queue
+	audioClient *client.AudioProcessorClient
+	dbPool      *pgxpool.Pool
+	admin       *admin.Server
+	log         *zap.Logger
+	// stopTracing отправляет накопленные спаны при остановке.
+	stopTracing func(context.Context) error
+	// stopBackground останавливает фоновые задачи, запущенные в Start.
+	stopBackground context.CancelFunc
+
+	state           atomic.Int32
+	updates         *updateTracker
+	shutdownTimeout time.Duration
 }
 
+var (
+	commands      = []string{"save_model", "models", "current", "say", "transcribe", "format", "history", "language", "start"}
+	groupCommands = []string{"say", "transcribe", "chat_settings"}
+	// hiddenCommands работают, но не показываются в меню.
+	hiddenCommands = []string{"choose_model", "delete_model"}
+)
+
+// setCommands регистрирует меню команд для личных чатов и групп на
+// каждом языке каталога. Меню без языка показывается остальным
+// пользователям на языке Fallback.
 func setCommands(b *telebot.Bot, logger *zap.Logger) {
-	commands := []telebot.Command{
-		{Text: "/save_model", Description: "Отправить голосовое сообщения для генерации модели."},
-		{Text: "/delete_model", Description: "Удалить модель"},
-		{Text: "/choose_model", Description: "Выбрать модель для генерации."},
-		{Text: "/start", Description: "Старт"},
+	scopes := []struct {
+		scope    telebot.CommandScope
+		commands []string
+	}{
+		{telebot.CommandScope{Type: telebot.CommandScopeAllPrivateChats}, commands},
+		{telebot.CommandScope{Type: telebot.CommandScopeAllGroupChats}, groupCommands},
 	}
 
-	err := b.SetCommands(commands)
-	if err != nil {
-		logger.Warn("Ошибка при установке команд", zap.Error(err))
+	for _, s := range scopes {
+		err := b.SetCommands(localizedCommands(i18n.Fallback, s.commands), s.scope)
+		if err != nil {
+			logger.Warn("Ошибка при установке команд", zap.String("scope", s.scope.Type), zap.Error(err))
+		}
+
+		for _, lang := range i18n.Languages {
+			err := b.SetCommands(localizedCommands(lang, s.commands), s.scope, lang)
+			if err != nil {
+				logger.Warn("Ошибка при установке команд", zap.String("scope", s.scope.Type), zap.String("lang", lang), zap.Error(err))
+			}
+		}
 	}
 }
 
+func localizedCommands(lang string, names []string) []telebot.Command {
+	result := make([]telebot.Command, 0, len(names))
+	for _, name := range names {
+		result = append(result, telebot.Command{Text: "/" + name, Description: i18n.T(lang, "command."+name)})
+	}
+	return result
+}
+
 func NewApp() *App {
-	return &App{}
+	return &App{updates: newUpdateTracker()}
 }
 
 func (a *App) Init() error {
@@ -48,27 +96,53 @@ func (a *App) Init() error {
 	a.log.Info("Инициализация приложения...")
 
 	cfg := config.LoadConfig()
+	a.shutdownTimeout = cfg.ShutdownTimeout
 
-	bot, err := telebot.NewBot(telebot.Settings{
-		Token:  cfg.TelegramToken,
-		Poller: &telebot.LongPoller{Timeout: 10 * time.Second},
+	a.stopTracing, err = tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.TracingExporter,
+		ServiceName: "telegram-bot",
+		SampleRatio: cfg.TracingSampleRatio,
 	})
 	if err != nil {
-		return fmt.Errorf("ошибка инициализации бота: %w", err)
+		return err
+	}
+	a.log.Info("Трейсинг настроен", zap.String("exporter", cfg.TracingExporter), zap.Float64("sampleRatio", cfg.TracingSampleRatio))
+
+	bot, err := a.newBot(cfg)
+	if err != nil {
+		return err
 	}
 	a.Bot = bot
-	a.log.Info("Бот успешно создан")
+	a.log.Info("Бот успешно создан", zap.String("updates", cfg.UpdatesMode))
 
-	audioClient, err := client.NewAudioProcessorClient(cfg.GRPCHost, cfg.GRPCPort)
+	endpoints, err := client.ParseEndpoints(cfg.GRPCServers, cfg.GRPCConcurrency)
+	if err != nil {
+		return fmt.Errorf("ошибка в списке серверов AudioProcessor: %w", err)
+	}
+	audioClient, err := client.NewAudioProcessorClient(endpoints, client.Security{
+		TLS:        cfg.GRPCTLS,
+		CAFile:     cfg.GRPCCAFile,
+		CertFile:   cfg.GRPCCertFile,
+		KeyFile:    cfg.GRPCKeyFile,
+		ServerName: cfg.GRPCServerName,
+		Token:      cfg.GRPCToken,
+	}, logger)
 	if err != nil {
 		return fmt.Errorf("ошибка создания gRPC клиента: %w", err)
 	}
-	a.log.Info("gRPC клиент успешно подключён")
+	a.audioClient = audioClient
+	a.log.Info("gRPC клиент создан, подключение к AudioProcessor в фоне", zap.Int("servers", len(endpoints)))
 
 	connString := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName)
 	a.log.Info("Подключение к базе данных...", zap.String("connString", connString))
 
-	dbPool, err := pgxpool.Connect(context.Background(), connString)
+	dbConfig, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return fmt.Errorf("ошибка разбора адреса базы данных: %w", err)
+	}
+	storage.TraceQueries(dbConfig)
+
+	dbPool, err := pgxpool.ConnectConfig(context.Background(), dbConfig)
 	if err != nil {
 		return fmt.Errorf("ошибка подключения к базе данных: %w", err)
 	}
@@ -77,27 +151,135 @@ func (a *App) Init() error {
 
 	postgres := storage.NewPostgresStorage(dbPool, logger)
 	svc := service.NewService(audioClient, postgres, logger)
-	controller := handler.NewHandler(svc, logger)
+	a.callbacks = callback.NewRouter(callback.NewCodec(cfg.CallbackSecret, defs.CallbackTTL), logger)
+	a.queue = queue.New(defs.QueueWorkers, logger)
+	controller := handler.NewHandler(svc, a.callbacks, a.queue, cfg.InlineCacheChatID, logger)
 
 	a.Handler = controller
+
+	a.admin, err = a.newAdminServer(cfg)
+	if err != nil {
+		return err
+	}
+	a.admin.Start()
 	a.log.Info("Инициализация компонентов приложения завершена")
 
 	return nil
 }
 
+// newBot создаёт бота, который получает обновления способом из
+// cfg.UpdatesMode.
+func (a *App) newBot(cfg config.Config) (*telebot.Bot, error) {
+	// Обработчики запускает dispatchPoller, поэтому внутри telebot
+	// они выполняются синхронно.
+	settings := telebot.Settings{Token: cfg.TelegramToken, Synchronous: true}
+
+	var webhook *webhookPoller
+	switch cfg.UpdatesMode {
+	case config.UpdatesPolling:
+		settings.Poller = &telebot.LongPoller{Timeout: 10 * time.Second}
+	case config.UpdatesWebhook:
+		var err error
+		webhook, err = newWebhookPoller(cfg, a.log)
+		if err != nil {
+			return nil, err
+		}
+		settings.Poller = webhook
+	default:
+		return nil, fmt.Errorf("неизвестный способ получения обновлений %q", cfg.UpdatesMode)
+	}
+
+	settings.Poller = &dispatchPoller{poller: settings.Poller, tracker: a.updates}
+
+	bot, err := telebot.NewBot(settings)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка инициализации бота: %w", err)
+	}
+
+	if webhook != nil {
+		if err := webhook.register(bot); err != nil {
+			return nil, err
+		}
+		return bot, nil
+	}
+
+	// Пока зарегистрирован вебхук, Telegram не отдаёт обновления
+	// long polling'у.
+	info, err := bot.Webhook()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения состояния вебхука: %w", err)
+	}
+	if info.Listen != "" {
+		if err := bot.RemoveWebhook(); err != nil {
+			return nil, fmt.Errorf("ошибка удаления вебхука: %w", err)
+		}
+		a.log.Info("Вебхук удалён для работы через long polling", zap.String("url", info.Listen))
+	}
+	return bot, nil
+}
+
+// Start регистрирует обработчики и запускает бота в фоне. Остановка —
+// Shutdown.
 func (a *App) Start() {
 	a.log.Info("Запуск приложения...")
 	setCommands(a.Bot, a.log)
 
+	observed := slices.Concat(commands, groupCommands, hiddenCommands)
+	a.Bot.Use(handler.ObserveUpdates(observed...), handler.TraceUpdates, a.Handler.Localize)
+
 	a.Bot.Handle("/save_model", a.Handler.GetModelName)
-	a.Bot.Handle("/delete_model", a.Handler.GetModelName)
-	a.Bot.Handle("/choose_model", a.Handler.GetUserModels)
+	a.Bot.Handle("/models", a.Handler.ShowModels)
+	a.Bot.Handle("/choose_model", a.Handler.ShowModels)
+	a.Bot.Handle("/delete_model", a.Handler.ShowModels)
+	a.Bot.Handle("/current", a.Handler.Current)
+	a.Bot.Handle("/language", a.Handler.ChooseLanguage)
+	a.Bot.Handle("/format", a.Handler.ChooseFormat)
+	a.Bot.Handle("/history", a.Handler.ShowHistory)
+	a.Bot.Handle("/say", a.Handler.Say)
+	a.Bot.Handle("/transcribe", a.Handler.Transcribe)
+	a.Bot.Handle("/chat_settings", a.Handler.ChatSettings)
 	a.Bot.Handle("/start", a.Handler.Start)
 
 	a.Bot.Handle(telebot.OnText, a.Handler.HandleText)
 	a.Bot.Handle(telebot.OnVoice, a.Handler.HandleVoice)
-	a.Bot.Handle(telebot.OnCallback, a.Handler.OnChooseModel)
+	a.Bot.Handle(telebot.OnAudio, a.Handler.HandleVoice)
+	a.Bot.Handle(telebot.OnVideoNote, a.Handler.HandleVoice)
+	a.Bot.Handle(telebot.OnVideo, a.Handler.HandleVoice)
+	a.Bot.Handle(telebot.OnDocument, a.Handler.HandleDocument)
+	a.Bot.Handle(telebot.OnQuery, a.Handler.OnInlineQuery)
+
+	a.Bot.Handle(telebot.OnCallback, a.callbacks.OnCallback)
+
+	a.callbacks.Handle(handler.ActionModelsPage, a.Handler.OnModelsPage)
+	a.callbacks.Handle(handler.ActionModelOpen, a.Handler.OnModelOpen)
+	a.callbacks.Handle(handler.ActionModelSelect, a.Handler.OnModelSelect)
+	a.callbacks.Handle(handler.ActionModelPreview, a.Handler.OnModelPreview)
+	a.callbacks.Handle(handler.ActionModelRename, a.Handler.OnModelRename)
+	a.callbacks.Handle(handler.ActionModelSample, a.Handler.OnModelSample)
+	a.callbacks.Handle(handler.ActionModelDelete, a.Handler.OnModelDelete)
+	a.callbacks.Handle(handler.ActionModelDeleteConfirm, a.Handler.OnModelDeleteConfirm)
+	a.callbacks.Handle(handler.ActionLanguage, a.Handler.OnLanguage)
+	a.callbacks.Handle(handler.ActionChatAdminOnly, a.Handler.OnChatAdminOnly)
+	a.callbacks.Handle(handler.ActionChatModel, a.Handler.OnChatModel)
+	a.callbacks.Handle(handler.ActionOutputFormat, a.Handler.OnOutputFormat)
+	a.callbacks.Handle(handler.ActionHistoryPage, a.Handler.OnHistoryPage)
+	a.callbacks.Handle(handler.ActionHistorySend, a.Handler.OnHistorySend)
+	a.callbacks.Handle(handler.ActionHistoryRegenerate, a.Handler.OnHistoryRegenerate)
+	a.callbacks.Handle(handler.ActionHistorySettings, a.Handler.OnHistorySettings)
+	a.callbacks.Handle(handler.ActionHistoryDays, a.Handler.OnHistoryDays)
+	a.callbacks.Handle(handler.ActionSpeechSlower, a.Handler.OnSpeechSlower)
+	a.callbacks.Handle(handler.ActionSpeechFaster, a.Handler.OnSpeechFaster)
+	a.callbacks.Handle(handler.ActionSpeechModels, a.Handler.OnSpeechModels)
+	a.callbacks.Handle(handler.ActionSpeechModel, a.Handler.OnSpeechModel)
+	a.callbacks.Handle(handler.ActionSpeechBack, a.Handler.OnSpeechBack)
+
+	var background context.Context
+	background, a.stopBackground = context.WithCancel(context.Background())
+
+	a.queue.Start()
+	go a.Handler.ResumeAudiobooks(background, a.Bot)
 
+	go a.Bot.Start()
+	a.setState(StateRunning)
 	a.log.Info("Бот готов к работе")
-	a.Bot.Start()
 }
@@ -0,0 +1,219 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"gopkg.in/telebot.v3"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// State — этап жизненного цикла приложения.
+type State int32
+
+const (
+	// StateStarting — приложение инициализируется.
+	StateStarting State = iota
+	// StateRunning — бот принимает и обрабатывает обновления.
+	StateRunning
+	// StateDraining — бот остановлен и дорабатывает начатое.
+	StateDraining
+	// StateStopped — соединения закрыты.
+	StateStopped
+)
+
+func (s State) String() string {
+	switch s {
+	case StateStarting:
+		return "starting"
+	case StateRunning:
+		return "running"
+	case StateDraining:
+		return "draining"
+	case StateStopped:
+		return "stopped"
+	}
+	return fmt.Sprintf("State(%d)", int32(s))
+}
+
+// State возвращает текущий этап жизненного цикла.
+func (a *App) State() State {
+	return State(a.state.Load())
+}
+
+// Live сообщает, что процесс работает: при запуске и остановке тоже.
+func (a *App) Live() bool {
+	return a.State() != StateStopped
+}
+
+// Ready сообщает, что бот принимает обновления.
+func (a *App) Ready() bool {
+	return a.State() == StateRunning
+}
+
+func (a *App) setState(s State) {
+	a.state.Store(int32(s))
+	a.log.Info("Состояние приложения изменилось", zap.Stringer("state", s))
+}
+
+// Run запускает бота и работает до SIGTERM или SIGINT, после чего
+// останавливает приложение с дедлайном из SHUTDOWN_TIMEOUT.
+func (a *App) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	a.Start()
+	<-ctx.Done()
+	// Повторный сигнал завершает процесс сразу.
+	stop()
+
+	a.log.Info("Получен сигнал остановки", zap.Duration("timeout", a.shutdownTimeout))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout)
+	defer cancel()
+	return a.Shutdown(shutdownCtx)
+}
+
+// Shutdown останавливает приложение: перестаёт принимать обновления,
+// ждёт обработки начатых и выполняемых задач очереди до дедлайна ctx и
+// закрывает соединения. Задачи, которые не успели начаться, например,
+// главы книг, продолжатся после перезапуска.
+func (a *App) Shutdown(ctx context.Context) error {
+	a.setState(StateDraining)
+
+	// После Stop поллер не получает новых обновлений, а вебхук отвечает
+	// Telegram ошибкой, и тот повторит обновление позже. Все
+	// принятые до этого обновления уже учтены в a.updates.
+	a.Bot.Stop()
+	a.stopBackground()
+	a.log.Info("Приём обновлений остановлен")
+
+	var errs []error
+	if err := a.updates.wait(ctx); err != nil {
+		a.log.Warn("Обработка обновлений не завершилась вовремя", zap.Int("updates", a.updates.count()))
+		errs = append(errs, fmt.Errorf("ожидание обработки обновлений: %w", err))
+	}
+
+	dropped := a.queue.Shutdown(ctx)
+	a.log.Info("Очередь задач остановлена", zap.Int("postponed", dropped))
+	if ctx.Err() != nil && len(errs) == 0 {
+		errs = append(errs, fmt.Errorf("остановка очереди: %w", ctx.Err()))
+	}
+
+	// Дедлайн ctx может истечь, а аренда должна сняться, чтобы книги
+	// сразу подхватили другие копии.
+	releaseCtx, cancelRelease := context.WithTimeout(context.Background(), 5*time.Second)
+	a.Handler.ReleaseAudiobooks(releaseCtx)
+	cancelRelease()
+	a.audioClient.Close()
+	a.dbPool.Close()
+	a.log.Info("Соединения закрыты")
+
+	a.setState(StateStopped)
+	// Служебный сервер работает до конца, чтобы оркестратор видел, что
+	// бот останавливается, а не завис. Дедлайн ctx к этому моменту
+	// может истечь, поэтому у сервера свой.
+	adminCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := a.admin.Shutdown(adminCtx); err != nil {
+		a.log.Warn("Ошибка остановки служебного сервера", zap.Error(err))
+	}
+	if err := a.stopTracing(adminCtx); err != nil {
+		a.log.Warn("Ошибка отправки трейсов", zap.Error(err))
+	}
+	_ = a.log.Sync()
+	return errors.Join(errs...)
+}
+
+// updateTracker считает обновления, которые обрабатываются сейчас,
+// чтобы при остановке дождаться их завершения.
+type updateTracker struct {
+	mu     sync.Mutex
+	active int
+	// idle закрывается и заменяется, когда счётчик становится нулевым.
+	idle chan struct{}
+}
+
+func newUpdateTracker() *updateTracker {
+	return &updateTracker{idle: make(chan struct{})}
+}
+
+func (t *updateTracker) add() {
+	t.mu.Lock()
+	t.active++
+	t.mu.Unlock()
+}
+
+func (t *updateTracker) done() {
+	t.mu.Lock()
+	t.active--
+	if t.active == 0 {
+		close(t.idle)
+		t.idle = make(chan struct{})
+	}
+	t.mu.Unlock()
+}
+
+func (t *updateTracker) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.active
+}
+
+// wait ждёт, пока не останется обрабатываемых обновлений, или
+// отмены ctx.
+func (t *updateTracker) wait(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+		active, idle := t.active, t.idle
+		t.mu.Unlock()
+
+		if active == 0 {
+			return nil
+		}
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// dispatchPoller передаёт обновления от poller обработчикам сам, а не
+// через буферизованный канал Bot.Updates: обновления, оставшиеся в
+// буфере после Bot.Stop, терялись бы. Канал от poller не
+// буферизован, поэтому обновление, которое poller отдал, уже учтено в
+// tracker, а Poll возвращается только после остановки poller, то
+// есть к возврату Bot.Stop все полученные обновления учтены.
+// Обработчики бота должны быть синхронными (Settings.Synchronous), а
+// параллельность даёт горутина на каждое обновление.
+type dispatchPoller struct {
+	poller  telebot.Poller
+	tracker *updateTracker
+}
+
+func (p *dispatchPoller) Poll(b *telebot.Bot, _ chan telebot.Update, stop chan struct{}) {
+	updates := make(chan telebot.Update)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.poller.Poll(b, updates, stop)
+	}()
+
+	for {
+		select {
+		case update := <-updates:
+			p.tracker.add()
+			go func() {
+				defer p.tracker.done()
+				b.ProcessUpdate(update)
+			}()
+		case <-done:
+			return
+		}
+	}
+}
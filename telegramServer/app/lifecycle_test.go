@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gopkg.in/telebot.v3"
+)
+
+// fakePoller отдаёт обновления и останавливается по stop.
+type fakePoller struct {
+	updates []telebot.Update
+}
+
+func (p *fakePoller) Poll(b *telebot.Bot, dest chan telebot.Update, stop chan struct{}) {
+	for _, update := range p.updates {
+		dest <- update
+	}
+	<-stop
+}
+
+func TestDispatchPollerTracksUpdates(t *testing.T) {
+	tracker := newUpdateTracker()
+	poller := &dispatchPoller{
+		poller: &fakePoller{updates: []telebot.Update{
+			{ID: 1, Message: &telebot.Message{Text: "первое", Chat: &telebot.Chat{ID: 1}}},
+			{ID: 2, Message: &telebot.Message{Text: "второе", Chat: &telebot.Chat{ID: 1}}},
+			// Обновление без обработчика тоже учитывается.
+			{ID: 3},
+		}},
+		tracker: tracker,
+	}
+	bot, err := telebot.NewBot(telebot.Settings{Offline: true, Synchronous: true, Poller: poller})
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	bot.Handle(telebot.OnText, func(c telebot.Context) error {
+		<-release
+		return nil
+	})
+
+	go bot.Start()
+	deadline := time.Now().Add(5 * time.Second)
+	for tracker.count() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("учтено %d обновлений, ожидалось 2", tracker.count())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	bot.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := tracker.wait(ctx); err == nil {
+		t.Fatalf("wait вернулся, пока обрабатывается %d обновлений", tracker.count())
+	}
+
+	close(release)
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracker.wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,203 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"gopkg.in/telebot.v3"
+	"kursach/config"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// Обновление с документом или длинным текстом укладывается в
+	// несколько килобайт, больший запрос — не от Telegram.
+	maxUpdateSize = 1 << 20
+
+	webhookShutdownTimeout = 10 * time.Second
+	secretTokenHeader      = "X-Telegram-Bot-Api-Secret-Token"
+)
+
+// webhookPoller получает обновления через вебхук: Telegram отправляет
+// их POST-запросами на публичный адрес, например, балансировщика перед
+// несколькими копиями бота.
+type webhookPoller struct {
+	hook         *telebot.Webhook
+	path         string
+	secret       []byte
+	deleteOnStop bool
+	tlsCert      string
+	tlsKey       string
+
+	listener net.Listener
+	server   *http.Server
+	log      *zap.Logger
+
+	dest    chan<- telebot.Update
+	stopped chan struct{}
+}
+
+// newWebhookPoller проверяет настройки и занимает порт, чтобы ошибки
+// обнаружились при запуске, а не после регистрации вебхука.
+func newWebhookPoller(cfg config.Config, logger *zap.Logger) (*webhookPoller, error) {
+	public, err := url.Parse(cfg.WebhookURL)
+	if err != nil || public.Scheme != "https" || public.Host == "" {
+		return nil, fmt.Errorf("WEBHOOK_URL должен быть адресом https://, получено %q", cfg.WebhookURL)
+	}
+	if (cfg.WebhookTLSCert == "") != (cfg.WebhookTLSKey == "") {
+		return nil, errors.New("для TLS нужны и WEBHOOK_TLS_CERT, и WEBHOOK_TLS_KEY")
+	}
+
+	secret := cfg.WebhookSecret
+	if secret == "" {
+		secret = defaultWebhookSecret(cfg.TelegramToken)
+	}
+
+	listener, err := net.Listen("tcp", cfg.WebhookListen)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия порта вебхука: %w", err)
+	}
+
+	path := public.Path
+	if path == "" {
+		path = "/"
+	}
+
+	p := &webhookPoller{
+		hook: &telebot.Webhook{
+			MaxConnections: cfg.WebhookMaxConnections,
+			SecretToken:    secret,
+			Endpoint: &telebot.WebhookEndpoint{
+				PublicURL: cfg.WebhookURL,
+				// Самоподписанный сертификат загружается в Telegram,
+				// иначе он не доверяет серверу.
+				Cert: cfg.WebhookCert,
+			},
+		},
+		path:         path,
+		secret:       []byte(secret),
+		deleteOnStop: cfg.WebhookDeleteOnStop,
+		tlsCert:      cfg.WebhookTLSCert,
+		tlsKey:       cfg.WebhookTLSKey,
+		listener:     listener,
+		log:          logger,
+		stopped:      make(chan struct{}),
+	}
+	p.server = &http.Server{
+		Handler:           p,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return p, nil
+}
+
+// defaultWebhookSecret выводит секретный токен из токена бота, чтобы
+// вебхук был защищён и без отдельной настройки, а все копии бота
+// получили одинаковый токен. Telegram допускает в нём только буквы,
+// цифры, "_" и "-".
+func defaultWebhookSecret(botToken string) string {
+	sum := sha256.Sum256([]byte("webhook:" + botToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// register сообщает Telegram адрес вебхука. Повторная регистрация тем
+// же адресом, например, при запуске ещё одной копии, безопасна.
+func (p *webhookPoller) register(b *telebot.Bot) error {
+	if err := b.SetWebhook(p.hook); err != nil {
+		return fmt.Errorf("ошибка регистрации вебхука: %w", err)
+	}
+	p.log.Info("Вебхук зарегистрирован", zap.String("url", p.hook.Endpoint.PublicURL), zap.Bool("customCert", p.hook.Endpoint.Cert != ""))
+	return nil
+}
+
+func (p *webhookPoller) Poll(b *telebot.Bot, dest chan telebot.Update, stop chan struct{}) {
+	p.dest = dest
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var err error
+		if p.tlsCert != "" {
+			err = p.server.ServeTLS(p.listener, p.tlsCert, p.tlsKey)
+		} else {
+			err = p.server.Serve(p.listener)
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			p.log.Error("Сервер вебхука остановлен с ошибкой", zap.Error(err))
+		}
+	}()
+	p.log.Info("Сервер вебхука запущен", zap.String("addr", p.listener.Addr().String()), zap.String("path", p.path))
+
+	<-stop
+	close(p.stopped)
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+	defer cancel()
+	if err := p.server.Shutdown(ctx); err != nil {
+		p.log.Warn("Ошибка остановки сервера вебхука", zap.Error(err))
+	}
+	<-done
+
+	// Остальные копии бота продолжают получать обновления, поэтому по
+	// умолчанию вебхук при остановке не удаляется.
+	if p.deleteOnStop {
+		if err := b.RemoveWebhook(); err != nil {
+			p.log.Warn("Ошибка удаления вебхука", zap.Error(err))
+		} else {
+			p.log.Info("Вебхук удалён")
+		}
+	}
+}
+
+func (p *webhookPoller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != p.path {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(secretTokenHeader)), p.secret) != 1 {
+		p.log.Warn("Запрос к вебхуку с неверным секретным токеном", zap.String("remote", r.RemoteAddr))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var update telebot.Update
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxUpdateSize)).Decode(&update); err != nil {
+		p.log.Warn("Некорректное обновление в вебхуке", zap.Error(err))
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	// При остановке обновление не принимается: Telegram повторит его
+	// позже, и его обработает другая копия бота. Без этой проверки
+	// select ниже мог бы выбрать отправку и после остановки.
+	select {
+	case <-p.stopped:
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	default:
+	}
+
+	// dest не буферизован, и dispatchPoller читает его, пока Poll не
+	// вернётся, а Poll сначала ждёт завершения запросов к вебхуку.
+	// Поэтому отправленное обновление уже учтено работающим ботом, и
+	// ответ 200 не теряет его даже при остановке.
+	select {
+	case p.dest <- update:
+		w.WriteHeader(http.StatusOK)
+	case <-p.stopped:
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+	case <-r.Context().Done():
+	}
+}
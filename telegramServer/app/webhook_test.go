@@ -0,0 +1,60 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"gopkg.in/telebot.v3"
+)
+
+func newTestWebhook(dest chan telebot.Update) *webhookPoller {
+	return &webhookPoller{
+		path:    "/hook",
+		secret:  []byte("secret"),
+		log:     zap.NewNop(),
+		dest:    dest,
+		stopped: make(chan struct{}),
+	}
+}
+
+func postUpdate(p *webhookPoller) int {
+	r := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(`{"update_id": 1}`))
+	r.Header.Set(secretTokenHeader, "secret")
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestWebhookDelivers(t *testing.T) {
+	dest := make(chan telebot.Update)
+	p := newTestWebhook(dest)
+
+	received := make(chan telebot.Update, 1)
+	go func() { received <- <-dest }()
+	if code := postUpdate(p); code != http.StatusOK {
+		t.Fatalf("код %d, ожидался %d", code, http.StatusOK)
+	}
+	if update := <-received; update.ID != 1 {
+		t.Fatalf("получено обновление %d, ожидалось 1", update.ID)
+	}
+}
+
+func TestWebhookRejectsAfterStop(t *testing.T) {
+	// Получатель готов принять обновление, но после остановки вебхук
+	// не должен его отдавать.
+	dest := make(chan telebot.Update, 1)
+	p := newTestWebhook(dest)
+	close(p.stopped)
+
+	for range 100 {
+		if code := postUpdate(p); code != http.StatusServiceUnavailable {
+			t.Fatalf("код %d, ожидался %d", code, http.StatusServiceUnavailable)
+		}
+	}
+	if len(dest) != 0 {
+		t.Fatal("обновление отдано боту после остановки")
+	}
+}
@@ -0,0 +1,162 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// Длинные записи, например, главы книг, собираются из фрагментов в
+// несжатом PCM: моно, 16 бит, SampleRate Гц.
+const (
+	SampleRate     = 24000
+	bytesPerSample = 2
+)
+
+// Tags — метаданные, которые записываются в MP3.
+type Tags struct {
+	Title  string
+	Album  string
+	Artist string
+	Track  int
+}
+
+// DecodePCM декодирует аудио любого формата, который понимает ffmpeg,
+// и дописывает его в w в виде PCM.
+func DecodePCM(ctx context.Context, in []byte, w io.Writer) (err error) {
+	ctx, done := track(ctx, "decode_pcm")
+	defer func() { done(err) }()
+
+	ff := exec.CommandContext(ctx, "ffmpeg",
+		"-loglevel", "error",
+		"-i", "pipe:0",
+		"-f", "s16le",
+		"-ac", "1",
+		"-ar", strconv.Itoa(SampleRate),
+		"pipe:1",
+	)
+	ff.Stdin = bytes.NewReader(in)
+	ff.Stdout = w
+
+	if err := ff.Run(); err != nil {
+		return fmt.Errorf("ffmpeg decode: %w", err)
+	}
+	return nil
+}
+
+// Silence дописывает в w тишину длительностью d.
+func Silence(w io.Writer, d time.Duration) error {
+	samples := int(d.Seconds() * SampleRate)
+	_, err := w.Write(make([]byte, samples*bytesPerSample))
+	return err
+}
+
+// PCMDuration возвращает длительность PCM размером size байт.
+func PCMDuration(size int64) time.Duration {
+	return time.Duration(size) * time.Second / (SampleRate * bytesPerSample)
+}
+
+// EncodeMP3 кодирует PCM из pcm в MP3 с тегами ID3.
+func EncodeMP3(ctx context.Context, pcm io.Reader, tags Tags) (_ []byte, err error) {
+	ctx, done := track(ctx, "encode_mp3")
+	defer func() { done(err) }()
+
+	args := []string{
+		"-loglevel", "error",
+		"-f", "s16le",
+		"-ac", "1",
+		"-ar", strconv.Itoa(SampleRate),
+		"-i", "pipe:0",
+		"-c:a", "libmp3lame",
+		"-b:a", "48k",
+		"-id3v2_version", "3",
+	}
+	args = append(args, tags.args()...)
+	args = append(args, "-f", "mp3", "pipe:1")
+
+	var out bytes.Buffer
+	ff := exec.CommandContext(ctx, "ffmpeg", args...)
+	ff.Stdin = pcm
+	ff.Stdout = &out
+
+	if err := ff.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg mp3: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// MaxSampleDuration ограничивает длину образца голоса: XTTS всё равно
+// использует только начало записи, а длинные видео долго кодировать.
+const MaxSampleDuration = 5 * time.Minute
+
+// NormalizeSample извлекает звуковую дорожку из голосового, аудио или
+// видео, выравнивает громкость и кодирует её в Ogg/Opus моно 48 кГц —
+// формат, в котором хранятся образцы моделей.
+func NormalizeSample(ctx context.Context, in []byte) (_ []byte, err error) {
+	ctx, done := track(ctx, "normalize_sample")
+	defer func() { done(err) }()
+
+	ff := exec.CommandContext(ctx, "ffmpeg",
+		"-loglevel", "error",
+		"-i", "pipe:0",
+		"-vn",
+		"-t", strconv.Itoa(int(MaxSampleDuration.Seconds())),
+		"-af", "loudnorm=I=-16:TP=-1.5:LRA=11",
+		"-c:a", "libopus",
+		"-b:a", "64k",
+		"-ac", "1",
+		"-ar", "48000",
+		"-f", "ogg",
+		"pipe:1",
+	)
+	ff.Stdin = bytes.NewReader(in)
+
+	var out bytes.Buffer
+	ff.Stdout = &out
+	if err := ff.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg normalize: %w", err)
+	}
+	if out.Len() == 0 {
+		return nil, errors.New("ffmpeg normalize: в файле нет звука")
+	}
+	return out.Bytes(), nil
+}
+
+// Concat склеивает записи из файлов paths одну за другой в Ogg/Opus.
+func Concat(ctx context.Context, paths ...string) (_ []byte, err error) {
+	ctx, done := track(ctx, "concat")
+	defer func() { done(err) }()
+
+	args := []string{"-loglevel", "error"}
+	filter := ""
+	for i, path := range paths {
+		args = append(args, "-i", path)
+		filter += fmt.Sprintf("[%d:a]aresample=48000,aformat=channel_layouts=mono[a%d];", i, i)
+	}
+	for i := range paths {
+		filter += fmt.Sprintf("[a%d]", i)
+	}
+	filter += fmt.Sprintf("concat=n=%d:v=0:a=1[out]", len(paths))
+
+	args = append(args,
+		"-filter_complex", filter,
+		"-map", "[out]",
+		"-c:a", "libopus",
+		"-b:a", "64k",
+		"-f", "ogg",
+		"pipe:1",
+	)
+
+	var out bytes.Buffer
+	ff := exec.CommandContext(ctx, "ffmpeg", args...)
+	ff.Stdout = &out
+	if err := ff.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg concat: %w", err)
+	}
+	return out.Bytes(), nil
+}
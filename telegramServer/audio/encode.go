@@ -0,0 +1,205 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// VideoNoteSize — сторона квадратного видеосообщения в пикселях.
+const VideoNoteSize = 384
+
+const ffprobeFmt = "default=noprint_wrappers=1:nokey=1"
+
+// ToVoice приводит аудио к формату голосового сообщения Telegram:
+// Opus моно 48 кГц в Ogg. Уже подходящие записи не перекодируются.
+func ToVoice(ctx context.Context, in []byte) (_ []byte, _ int, err error) {
+	ctx, done := track(ctx, "voice")
+	defer func() { done(err) }()
+
+	infoCmd := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-select_streams", "a:0",
+		"-show_entries", "stream=codec_name,channels,sample_rate",
+		"-of", ffprobeFmt,
+		"pipe:0",
+	)
+	infoCmd.Stdin = bytes.NewReader(in)
+	infoOut, _ := infoCmd.Output()
+
+	needRecode := true
+	if len(infoOut) > 0 {
+		fields := strings.Split(strings.TrimSpace(string(infoOut)), "\n")
+		if len(fields) == 3 &&
+			fields[0] == "opus" &&
+			fields[1] == "1" &&
+			fields[2] == "48000" {
+			needRecode = false
+		}
+	}
+
+	ogg := in
+	if needRecode {
+		ogg, err = transcode(ctx, in,
+			"-c:a", "libopus",
+			"-application", "voip",
+			"-b:a", "64k",
+			"-ac", "1",
+			"-ar", "48000",
+			"-f", "ogg",
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	dur, err := Duration(ctx, ogg)
+	if err != nil {
+		return nil, 0, err
+	}
+	return ogg, dur, nil
+}
+
+// ToMP3 кодирует аудио в MP3 с тегами ID3.
+func ToMP3(ctx context.Context, in []byte, tags Tags) (_ []byte, _ int, err error) {
+	ctx, done := track(ctx, "mp3")
+	defer func() { done(err) }()
+
+	args := append([]string{"-c:a", "libmp3lame", "-b:a", "128k", "-id3v2_version", "3"}, tags.args()...)
+	out, err := transcode(ctx, in, append(args, "-f", "mp3")...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return withDuration(ctx, out)
+}
+
+// ToM4A кодирует аудио в AAC в контейнере MP4 с метаданными.
+func ToM4A(ctx context.Context, in []byte, tags Tags) (_ []byte, _ int, err error) {
+	ctx, done := track(ctx, "m4a")
+	defer func() { done(err) }()
+
+	args := append([]string{"-vn", "-c:a", "aac", "-b:a", "128k"}, tags.args()...)
+	out, err := transcodeFile(ctx, in, ".m4a", append(args, "-movflags", "+faststart")...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return withDuration(ctx, out)
+}
+
+// ToWAV декодирует аудио в несжатый WAV для редактирования.
+func ToWAV(ctx context.Context, in []byte) (_ []byte, _ int, err error) {
+	ctx, done := track(ctx, "wav")
+	defer func() { done(err) }()
+
+	out, err := transcode(ctx, in, "-c:a", "pcm_s16le", "-f", "wav")
+	if err != nil {
+		return nil, 0, err
+	}
+	return withDuration(ctx, out)
+}
+
+// ToVideoNote собирает круглое видеосообщение: квадратное видео с
+// волной звука на фоне цвета Telegram.
+func ToVideoNote(ctx context.Context, in []byte) (_ []byte, _ int, err error) {
+	ctx, done := track(ctx, "video_note")
+	defer func() { done(err) }()
+
+	filter := fmt.Sprintf(
+		"color=c=0x2AABEE:s=%[1]dx%[1]d:r=25[bg];"+
+			"[0:a]aformat=channel_layouts=mono,showwaves=s=%[1]dx%[2]d:mode=cline:rate=25:colors=white[w];"+
+			"[bg][w]overlay=0:(H-h)/2:shortest=1,format=yuv420p[v]",
+		VideoNoteSize, VideoNoteSize/2,
+	)
+	out, err := transcodeFile(ctx, in, ".mp4",
+		"-filter_complex", filter,
+		"-map", "[v]",
+		"-map", "0:a",
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-c:a", "aac",
+		"-b:a", "64k",
+		"-shortest",
+		"-movflags", "+faststart",
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	return withDuration(ctx, out)
+}
+
+// Duration возвращает длительность записи в секундах, округлённую до
+// ближайшего целого.
+func Duration(ctx context.Context, in []byte) (int, error) {
+	pr := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", ffprobeFmt,
+		"pipe:0",
+	)
+	pr.Stdin = bytes.NewReader(in)
+
+	durOut, err := pr.Output()
+	if err != nil {
+		return 0, errors.New("ffprobe: " + err.Error())
+	}
+	dur, _ := strconv.ParseFloat(strings.TrimSpace(string(durOut)), 64)
+	return int(dur + 0.5), nil
+}
+
+func withDuration(ctx context.Context, out []byte) ([]byte, int, error) {
+	dur, err := Duration(ctx, out)
+	if err != nil {
+		return nil, 0, err
+	}
+	return out, dur, nil
+}
+
+func (t Tags) args() []string {
+	var args []string
+	for _, tag := range []struct{ key, value string }{{"title", t.Title}, {"album", t.Album}, {"artist", t.Artist}} {
+		if tag.value != "" {
+			args = append(args, "-metadata", tag.key+"="+tag.value)
+		}
+	}
+	if t.Track > 0 {
+		args = append(args, "-metadata", "track="+strconv.Itoa(t.Track))
+	}
+	return args
+}
+
+// transcode пропускает аудио через ffmpeg с выходом в pipe.
+func transcode(ctx context.Context, in []byte, args ...string) ([]byte, error) {
+	ff := exec.CommandContext(ctx, "ffmpeg", append([]string{"-loglevel", "error", "-i", "pipe:0"}, append(args, "pipe:1")...)...)
+	ff.Stdin = bytes.NewReader(in)
+
+	var out bytes.Buffer
+	ff.Stdout = &out
+	if err := ff.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg recode: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// transcodeFile нужен для MP4: этому контейнеру нужен перемотываемый
+// выход, поэтому результат пишется во временный файл.
+func transcodeFile(ctx context.Context, in []byte, ext string, args ...string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "audio-*")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания временной директории: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	outPath := filepath.Join(dir, "out"+ext)
+	ff := exec.CommandContext(ctx, "ffmpeg", append([]string{"-loglevel", "error", "-i", "pipe:0"}, append(args, outPath)...)...)
+	ff.Stdin = bytes.NewReader(in)
+	if err := ff.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg recode: %w", err)
+	}
+	return os.ReadFile(outPath)
+}
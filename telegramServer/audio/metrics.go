@@ -0,0 +1,34 @@
+package audio
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"kursach/tracing"
+	"time"
+)
+
+var (
+	conversionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "audio_conversion_duration_seconds",
+		Help:    "Длительность обработки аудио в ffmpeg по операциям.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2.5, 12),
+	}, []string{"operation"})
+
+	tracer = otel.Tracer("kursach/audio")
+)
+
+func init() {
+	prometheus.MustRegister(conversionDuration)
+}
+
+// track начинает операцию operation: спан трейса и замер длительности.
+// Возвращённая функция завершает операцию с ошибкой err.
+func track(ctx context.Context, operation string) (context.Context, func(err error)) {
+	ctx, span := tracer.Start(ctx, "audio."+operation)
+	start := time.Now()
+	return ctx, func(err error) {
+		conversionDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+		tracing.End(span, err)
+	}
+}
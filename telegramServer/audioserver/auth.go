@@ -0,0 +1,24 @@
+package audioserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// CheckToken отклоняет запросы без заголовка
+// "authorization: Bearer <token>", как AUTH_TOKEN у pythonServer.
+func CheckToken(token string) grpc.UnaryServerInterceptor {
+	expected := []byte("Bearer " + token)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) == 0 || subtle.ConstantTimeCompare([]byte(values[0]), expected) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "неверный токен")
+		}
+		return handler(ctx, req)
+	}
+}
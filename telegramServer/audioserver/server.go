@@ -0,0 +1,169 @@
+// Package audioserver — эталонная реализация AudioProcessor без GPU и
+// нейросетей для локальной разработки, интеграционных тестов и демо.
+// Вместо голоса пользователя сервер озвучивает текст синтетическим
+// голосом, может отвечать с задержкой и имитировать сбои.
+package audioserver
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	pb "kursach/proto"
+	"math/rand/v2"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// requestIDHeader совпадает с client.RequestIDHeader.
+const requestIDHeader = "x-request-id"
+
+// Options задаёт поведение сервера.
+type Options struct {
+	// Latency — задержка каждого ответа, RuneLatency — дополнительная
+	// задержка синтеза на каждый символ текста.
+	Latency     time.Duration
+	RuneLatency time.Duration
+
+	// FailureRate — доля запросов, которые завершаются gRPC-ошибкой
+	// FailureCode, например, Unavailable, чтобы проверить повторы и
+	// переключение между серверами.
+	FailureRate float64
+	FailureCode codes.Code
+	// ErrorRate — доля запросов, на которые сервер отвечает статусом
+	// "ERROR", как AudioProcessor при ошибке модели.
+	ErrorRate float64
+
+	// Transcript — текст, который возвращает распознавание.
+	Transcript string
+
+	// Seed делает последовательность сбоев воспроизводимой.
+	Seed uint64
+}
+
+// Server реализует AudioProcessor.
+type Server struct {
+	pb.UnimplementedAudioProcessorServer
+
+	opts   Options
+	log    *zap.Logger
+	health *health.Server
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func New(opts Options, logger *zap.Logger) *Server {
+	if opts.FailureCode == codes.OK {
+		opts.FailureCode = codes.Unavailable
+	}
+	return &Server{
+		opts:   opts,
+		log:    logger,
+		health: health.NewServer(),
+		rand:   rand.New(rand.NewPCG(opts.Seed, 0)),
+	}
+}
+
+// Register регистрирует на srv сервис AudioProcessor и сервис проверки
+// здоровья grpc.health.v1.
+func (s *Server) Register(srv *grpc.Server) {
+	pb.RegisterAudioProcessorServer(srv, s)
+	healthpb.RegisterHealthServer(srv, s.health)
+	s.health.SetServingStatus(pb.AudioProcessor_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+}
+
+// Shutdown сообщает балансировщику бота, что сервер останавливается:
+// новые запросы перестают приходить, начатые дорабатываются.
+func (s *Server) Shutdown() {
+	s.health.Shutdown()
+}
+
+func (s *Server) ProcessContent(ctx context.Context, req *pb.ContentRequest) (*pb.ProcessingResponse, error) {
+	delay := s.opts.Latency + time.Duration(utf8.RuneCountInString(req.GetText()))*s.opts.RuneLatency
+	failed, err := s.simulate(ctx, delay)
+	if err != nil {
+		return nil, err
+	}
+	if failed {
+		return &pb.ProcessingResponse{Status: "ERROR: тестовый сбой синтеза", Result: &pb.AudioResult{}}, nil
+	}
+
+	speech := Synthesize(req.GetText(), req.GetAudio().GetData(), float64(req.GetSpeed()))
+	return &pb.ProcessingResponse{Status: "OK", Result: &pb.AudioResult{ProcessedAudio: speech}}, nil
+}
+
+func (s *Server) Transcribe(ctx context.Context, req *pb.TranscribeRequest) (*pb.TranscribeResponse, error) {
+	failed, err := s.simulate(ctx, s.opts.Latency)
+	if err != nil {
+		return nil, err
+	}
+	if failed {
+		return &pb.TranscribeResponse{Status: "ERROR: тестовый сбой распознавания"}, nil
+	}
+	return &pb.TranscribeResponse{Status: "OK", Text: s.opts.Transcript}, nil
+}
+
+// simulate выдерживает задержку delay и решает, завершится ли запрос
+// сбоем: gRPC-ошибкой или статусом "ERROR" (failed).
+func (s *Server) simulate(ctx context.Context, delay time.Duration) (failed bool, err error) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return false, status.FromContextError(ctx.Err()).Err()
+	}
+
+	s.mu.Lock()
+	fail := s.rand.Float64() < s.opts.FailureRate
+	failed = s.rand.Float64() < s.opts.ErrorRate
+	s.mu.Unlock()
+
+	if fail {
+		return false, status.Error(s.opts.FailureCode, "тестовый сбой")
+	}
+	return failed, nil
+}
+
+// LogRequests логирует запросы с ID, который передаёт бот, как это
+// делает AudioProcessor.
+func (s *Server) LogRequests(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	requestID := "-"
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDHeader); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, requestID))
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	if info.FullMethod == healthpb.Health_Check_FullMethodName || info.FullMethod == healthpb.Health_Watch_FullMethodName {
+		return resp, err
+	}
+
+	fields := []zap.Field{
+		zap.String("requestID", requestID),
+		zap.String("method", info.FullMethod),
+		zap.Duration("latency", time.Since(start)),
+		zap.String("code", status.Code(err).String()),
+	}
+	if result, ok := resp.(interface{ GetStatus() string }); ok {
+		fields = append(fields, zap.String("status", result.GetStatus()))
+	}
+	s.log.Info("Запрос обработан", fields...)
+	return resp, err
+}
+
+func (o Options) String() string {
+	return fmt.Sprintf("задержка %s + %s на символ, сбои %.0f%% (%s), ошибки %.0f%%",
+		o.Latency, o.RuneLatency, o.FailureRate*100, o.FailureCode, o.ErrorRate*100)
+}
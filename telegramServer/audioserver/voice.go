@@ -0,0 +1,235 @@
+package audioserver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/fnv"
+	"kursach/audio"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Длительности звуков при обычном темпе. Длина записи пропорциональна
+// длине текста.
+const (
+	vowelDuration     = 110 * time.Millisecond
+	consonantDuration = 60 * time.Millisecond
+	spaceDuration     = 70 * time.Millisecond
+	pauseDuration     = 250 * time.Millisecond
+	fadeDuration      = 8 * time.Millisecond
+)
+
+type soundKind int
+
+const (
+	silence soundKind = iota
+	vowel
+	voiced
+	unvoiced
+)
+
+type sound struct {
+	kind     soundKind
+	f1, f2   float64
+	duration time.Duration
+}
+
+// Форманты гласных, Гц.
+var vowels = map[rune][2]float64{
+	'a': {730, 1090}, 'а': {730, 1090}, 'я': {730, 1090},
+	'e': {530, 1840}, 'е': {530, 1840}, 'э': {530, 1840},
+	'i': {270, 2290}, 'и': {270, 2290},
+	'o': {570, 840}, 'о': {570, 840}, 'ё': {570, 840},
+	'u': {300, 870}, 'у': {300, 870}, 'ю': {300, 870},
+	'y': {300, 1600}, 'ы': {300, 1600},
+}
+
+const voicedConsonants = "bdgjlmnrvwzбвгджзйлмнр"
+
+// voice — параметры голоса, которые выводятся из образца: разные
+// образцы звучат по-разному, один и тот же — всегда одинаково.
+type voice struct {
+	pitch   float64
+	formant float64
+}
+
+func newVoice(sample []byte) voice {
+	h := fnv.New64a()
+	_, _ = h.Write(sample)
+	sum := h.Sum64()
+	return voice{
+		pitch:   90 + float64(sum%160),
+		formant: 0.85 + float64((sum>>8)%31)/100,
+	}
+}
+
+// Synthesize озвучивает text синтетическим голосом, похожим на речь:
+// гласные — импульсы основного тона через два форманта, согласные —
+// шум или приглушённый тон. Результат — WAV, моно, 16 бит,
+// audio.SampleRate Гц. Одинаковые входные данные дают одинаковый
+// результат.
+func Synthesize(text string, sample []byte, speed float64) []byte {
+	if speed <= 0 {
+		speed = 1
+	}
+	v := newVoice(sample)
+	sounds := parse(text, speed)
+
+	var total int
+	for _, s := range sounds {
+		total += samples(s.duration)
+	}
+	signal := make([]float64, 0, total)
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(text))
+	noise := rand.New(rand.NewPCG(h.Sum64(), 0))
+
+	var phase float64
+	for i, s := range sounds {
+		// Тон плавно понижается к концу фразы и слегка колеблется.
+		progress := float64(i) / float64(max(len(sounds), 1))
+		f0 := v.pitch * (1.1 - 0.2*progress)
+		signal = s.render(signal, v, f0, &phase, noise)
+	}
+
+	return wav(normalize(signal))
+}
+
+func parse(text string, speed float64) []sound {
+	var sounds []sound
+	scale := func(d time.Duration) time.Duration {
+		return time.Duration(float64(d) / speed)
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsSpace(r):
+			sounds = append(sounds, sound{kind: silence, duration: scale(spaceDuration)})
+		case strings.ContainsRune(".,!?;:…—", r):
+			sounds = append(sounds, sound{kind: silence, duration: scale(pauseDuration)})
+		case unicode.IsDigit(r):
+			f := vowels['a']
+			sounds = append(sounds, sound{kind: vowel, f1: f[0], f2: f[1], duration: scale(vowelDuration)})
+		case !unicode.IsLetter(r):
+			continue
+		default:
+			if f, ok := vowels[r]; ok {
+				sounds = append(sounds, sound{kind: vowel, f1: f[0], f2: f[1], duration: scale(vowelDuration)})
+			} else if strings.ContainsRune(voicedConsonants, r) {
+				sounds = append(sounds, sound{kind: voiced, f1: 250, f2: 1200, duration: scale(consonantDuration)})
+			} else {
+				sounds = append(sounds, sound{kind: unvoiced, duration: scale(consonantDuration)})
+			}
+		}
+	}
+	return sounds
+}
+
+func (s sound) render(signal []float64, v voice, f0 float64, phase *float64, noise *rand.Rand) []float64 {
+	n := samples(s.duration)
+	fade := samples(fadeDuration)
+	r1 := newResonator(s.f1*v.formant, 90)
+	r2 := newResonator(s.f2*v.formant, 110)
+
+	for i := 0; i < n; i++ {
+		var x float64
+		switch s.kind {
+		case vowel, voiced:
+			t := float64(len(signal)) / audio.SampleRate
+			f := f0 * (1 + 0.03*math.Sin(2*math.Pi*5*t))
+			*phase += f / audio.SampleRate
+			if *phase >= 1 {
+				*phase--
+			}
+			// Пилообразный сигнал похож на импульсы голосовых связок.
+			pulse := 1 - 2**phase
+			x = r1.next(pulse) + 0.5*r2.next(pulse)
+			if s.kind == voiced {
+				x *= 0.35
+			}
+		case unvoiced:
+			x = 0.3 * (2*noise.Float64() - 1)
+		}
+
+		// Плавные края убирают щелчки на стыках звуков.
+		gain := 1.0
+		if i < fade {
+			gain = float64(i) / float64(fade)
+		} else if n-i < fade {
+			gain = float64(n-i) / float64(fade)
+		}
+		signal = append(signal, x*gain)
+	}
+	return signal
+}
+
+// normalize приводит громкость к постоянному уровню: усиление
+// резонаторов зависит от формант и основного тона.
+func normalize(signal []float64) []int16 {
+	var peak float64
+	for _, x := range signal {
+		peak = max(peak, math.Abs(x))
+	}
+	scale := 0.0
+	if peak > 0 {
+		scale = 0.8 * math.MaxInt16 / peak
+	}
+
+	pcm := make([]int16, len(signal))
+	for i, x := range signal {
+		pcm[i] = int16(x * scale)
+	}
+	return pcm
+}
+
+// resonator — резонансный фильтр второго порядка, который выделяет
+// форманту с частотой freq и полосой bandwidth.
+type resonator struct {
+	a, b, c float64
+	y1, y2  float64
+}
+
+func newResonator(freq float64, bandwidth float64) *resonator {
+	const t = 1.0 / audio.SampleRate
+	c := -math.Exp(-2 * math.Pi * bandwidth * t)
+	b := 2 * math.Exp(-math.Pi*bandwidth*t) * math.Cos(2*math.Pi*freq*t)
+	return &resonator{a: 1 - b - c, b: b, c: c}
+}
+
+func (r *resonator) next(x float64) float64 {
+	y := r.a*x + r.b*r.y1 + r.c*r.y2
+	r.y2, r.y1 = r.y1, y
+	return y
+}
+
+func samples(d time.Duration) int {
+	return int(d.Seconds() * audio.SampleRate)
+}
+
+func wav(pcm []int16) []byte {
+	size := len(pcm) * 2
+	var buf bytes.Buffer
+	buf.Grow(44 + size)
+
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(36+size))
+	buf.WriteString("WAVEfmt ")
+	for _, field := range []any{
+		uint32(16),                   // размер блока fmt
+		uint16(1),                    // PCM
+		uint16(1),                    // моно
+		uint32(audio.SampleRate),     // частота
+		uint32(audio.SampleRate * 2), // байт в секунду
+		uint16(2),                    // байт на отсчёт
+		uint16(16),                   // бит на отсчёт
+	} {
+		_ = binary.Write(&buf, binary.LittleEndian, field)
+	}
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(size))
+	_ = binary.Write(&buf, binary.LittleEndian, pcm)
+	return buf.Bytes()
+}
@@ -0,0 +1,142 @@
+package book
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/text/encoding/charmap"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxChapterLen — максимальная длина главы в символах. Более длинные
+// главы делятся на части, чтобы файл главы помещался в лимит Telegram.
+const MaxChapterLen = 40000
+
+// MaxUnpackedSize — сколько байт можно распаковать из архива одной
+// книги, например, EPUB. Текст даже длинной книги занимает несколько
+// мегабайт.
+const MaxUnpackedSize = 32 << 20
+
+var (
+	ErrUnsupported = errors.New("неподдерживаемый формат файла")
+	ErrTooLarge    = errors.New("книга слишком большая после распаковки")
+)
+
+type Chapter struct {
+	// Title может быть пустым, если в файле у главы нет заголовка.
+	Title string
+	Text  string
+}
+
+type Book struct {
+	Title    string
+	Chapters []Chapter
+}
+
+// Supported сообщает, умеет ли пакет извлекать текст из файла с таким
+// именем.
+func Supported(fileName string) bool {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".txt", ".md", ".fb2", ".epub":
+		return true
+	}
+	return false
+}
+
+// Parse извлекает текст книги и делит его на главы. Формат определяется
+// по расширению файла.
+func Parse(fileName string, data []byte) (*Book, error) {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	title := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+
+	var (
+		b   *Book
+		err error
+	)
+	switch ext {
+	case ".txt":
+		b = parseText(title, decodeText(data))
+	case ".md":
+		b = parseMarkdown(title, decodeText(data))
+	case ".fb2":
+		b, err = parseFB2(title, data)
+	case ".epub":
+		b, err = parseEPUB(title, data)
+	default:
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора %s: %w", ext, err)
+	}
+
+	b.Chapters = splitLongChapters(b.Chapters)
+	if len(b.Chapters) == 0 {
+		return nil, errors.New("в файле нет текста")
+	}
+	return b, nil
+}
+
+// decodeText приводит текст к UTF-8. Русские .txt часто сохранены в
+// Windows-1251, поэтому невалидный UTF-8 считается этой кодировкой.
+func decodeText(data []byte) string {
+	data = trimBOM(data)
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	decoded, err := charmap.Windows1251.NewDecoder().Bytes(data)
+	if err != nil {
+		return strings.ToValidUTF8(string(data), "")
+	}
+	return string(decoded)
+}
+
+func trimBOM(data []byte) []byte {
+	if len(data) >= 3 && data[0] == 0xEF && data[1] == 0xBB && data[2] == 0xBF {
+		return data[3:]
+	}
+	return data
+}
+
+// appendChapter добавляет главу, если в ней есть текст.
+func appendChapter(chapters []Chapter, title, text string) []Chapter {
+	text = normalizeText(text)
+	if text == "" {
+		return chapters
+	}
+	return append(chapters, Chapter{Title: strings.Join(strings.Fields(title), " "), Text: text})
+}
+
+// normalizeText убирает пробелы в начале и конце строк и схлопывает
+// пустые строки, сохраняя деление на абзацы.
+func normalizeText(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	paragraphs := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			paragraphs = append(paragraphs, line)
+		}
+	}
+	return strings.Join(paragraphs, "\n")
+}
+
+func splitLongChapters(chapters []Chapter) []Chapter {
+	result := make([]Chapter, 0, len(chapters))
+	for _, chapter := range chapters {
+		if utf8.RuneCountInString(chapter.Text) <= MaxChapterLen {
+			result = append(result, chapter)
+			continue
+		}
+
+		parts := Segments(chapter.Text, MaxChapterLen)
+		for i, part := range parts {
+			title := chapter.Title
+			if title != "" {
+				title = fmt.Sprintf("%s (%d/%d)", title, i+1, len(parts))
+			}
+			result = append(result, Chapter{Title: title, Text: part})
+		}
+	}
+	return result
+}
@@ -0,0 +1,144 @@
+package book
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		data  string
+		title string
+		want  []Chapter
+	}{
+		{
+			name:  "текст без глав",
+			file:  "notes.txt",
+			data:  "Первая строка.\n\n  Вторая   строка.  \n",
+			title: "notes",
+			want:  []Chapter{{Text: "Первая строка.\nВторая строка."}},
+		},
+		{
+			name:  "текст с главами",
+			file:  "book.txt",
+			data:  "Предисловие.\r\nГлава 1\r\nНачало.\r\nCHAPTER 2: The End\r\nКонец.\r\nГлавами книги считаются строки вроде этой.",
+			title: "book",
+			want: []Chapter{
+				{Text: "Предисловие."},
+				{Title: "Глава 1", Text: "Начало."},
+				{Title: "CHAPTER 2: The End", Text: "Конец.\nГлавами книги считаются строки вроде этой."},
+			},
+		},
+		{
+			name: "пустая глава пропускается",
+			file: "book.txt",
+			data: "Глава 1\nГлава 2\nТекст.",
+			want: []Chapter{{Title: "Глава 2", Text: "Текст."}},
+		},
+		{
+			name: "markdown",
+			file: "readme.md",
+			data: "# Введение\nТекст со **ссылкой** на [сайт](https://example.com).\n\n" +
+				"```go\nfmt.Println()\n```\n---\n" +
+				"## Установка\n- шаг ![схема](img.png)\n> цитата\n#### Подробности ####\nещё",
+			want: []Chapter{
+				{Title: "Введение", Text: "Текст со ссылкой на сайт."},
+				{Title: "Установка", Text: "шаг схема\nцитата\nПодробности\nещё"},
+			},
+		},
+		{
+			name:  "fb2",
+			file:  "tale.fb2",
+			title: "Сказка",
+			data: `<?xml version="1.0" encoding="utf-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0">
+  <description><title-info><book-title>Сказка</book-title></title-info></description>
+  <body>
+    <section>
+      <title><p>Часть 1</p></title>
+      <section>
+        <title><p>Глава 1</p><p>Дорога</p></title>
+        <p>Жили&nbsp;были.</p>
+        <p>Дед и баба.</p>
+      </section>
+      <section>
+        <title><p>Глава 2</p></title>
+        <p>Снесла курочка яичко.</p>
+      </section>
+    </section>
+  </body>
+  <body name="notes">
+    <section><p>Примечание не читается.</p></section>
+  </body>
+  <binary id="cover.jpg">AAAA</binary>
+</FictionBook>`,
+			want: []Chapter{
+				{Title: "Часть 1. Глава 1. Дорога", Text: "Жили были.\nДед и баба."},
+				{Title: "Глава 2", Text: "Снесла курочка яичко."},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := Parse(tt.file, []byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.title != "" && b.Title != tt.title {
+				t.Errorf("название %q, ожидалось %q", b.Title, tt.title)
+			}
+			assertChapters(t, b.Chapters, tt.want)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := Parse("book.pdf", []byte("%PDF")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("pdf: ошибка %v, ожидалась %v", err, ErrUnsupported)
+	}
+	if _, err := Parse("empty.txt", []byte(" \n\n ")); err == nil {
+		t.Error("пустой файл разобран без ошибки")
+	}
+	if _, err := Parse("broken.epub", []byte("не архив")); err == nil {
+		t.Error("повреждённый EPUB разобран без ошибки")
+	}
+}
+
+func TestParseWindows1251(t *testing.T) {
+	data, err := charmap.Windows1251.NewEncoder().Bytes([]byte("Глава 1\nПривет, мир."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Parse("old.txt", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertChapters(t, b.Chapters, []Chapter{{Title: "Глава 1", Text: "Привет, мир."}})
+}
+
+func TestParseSplitsLongChapters(t *testing.T) {
+	sentence := strings.Repeat("слово ", 99) + "конец. "
+	text := "Глава 1\n" + strings.Repeat(sentence, MaxChapterLen/len([]rune(sentence))*2)
+
+	b, err := Parse("long.txt", []byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Chapters) < 2 {
+		t.Fatalf("глава не разделена: %d частей", len(b.Chapters))
+	}
+	for i, chapter := range b.Chapters {
+		if n := utf8.RuneCountInString(chapter.Text); n > MaxChapterLen {
+			t.Errorf("часть %d длиной %d символов", i+1, n)
+		}
+		if !strings.HasPrefix(chapter.Title, "Глава 1 (") {
+			t.Errorf("часть %d называется %q", i+1, chapter.Title)
+		}
+	}
+}
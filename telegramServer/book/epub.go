@@ -0,0 +1,189 @@
+package book
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Title    string `xml:"metadata>title"`
+	Manifest []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// zipBook читает файлы архива книги и ограничивает их общий
+// распакованный размер: небольшой архив может распаковаться в
+// гигабайты.
+type zipBook struct {
+	archive *zip.Reader
+	left    int64
+}
+
+func (z *zipBook) read(name string) ([]byte, error) {
+	f, err := z.archive.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия %s: %w", name, err)
+	}
+	defer f.Close()
+
+	// Размер из заголовка архива может быть неверным, поэтому чтение
+	// всё равно ограничено.
+	if info, err := f.Stat(); err == nil && info.Size() > z.left {
+		return nil, fmt.Errorf("%s: %w", name, ErrTooLarge)
+	}
+	data, err := io.ReadAll(io.LimitReader(f, z.left+1))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения %s: %w", name, err)
+	}
+	if int64(len(data)) > z.left {
+		return nil, fmt.Errorf("%s: %w", name, ErrTooLarge)
+	}
+	z.left -= int64(len(data))
+	return data, nil
+}
+
+func (z *zipBook) readXML(name string, v any) error {
+	data, err := z.read(name)
+	if err != nil {
+		return err
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.CharsetReader = charsetReader
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("ошибка разбора %s: %w", name, err)
+	}
+	return nil
+}
+
+// parseEPUB читает документы книги в порядке spine: каждый документ
+// с текстом становится главой, заголовком — первый h1–h3.
+func parseEPUB(title string, data []byte) (*Book, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	z := &zipBook{archive: archive, left: MaxUnpackedSize}
+
+	var container epubContainer
+	if err := z.readXML("META-INF/container.xml", &container); err != nil {
+		return nil, err
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, errors.New("в container.xml нет rootfile")
+	}
+
+	opfPath := container.Rootfiles[0].FullPath
+	var pkg epubPackage
+	if err := z.readXML(opfPath, &pkg); err != nil {
+		return nil, err
+	}
+
+	b := &Book{Title: title}
+	if t := strings.TrimSpace(pkg.Title); t != "" {
+		b.Title = t
+	}
+
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	for _, item := range pkg.Manifest {
+		if item.MediaType == "application/xhtml+xml" || item.MediaType == "text/html" {
+			hrefs[item.ID] = item.Href
+		}
+	}
+
+	for _, ref := range pkg.Spine {
+		href, ok := hrefs[ref.IDRef]
+		if !ok {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(href); err == nil {
+			href = unescaped
+		}
+
+		doc, err := z.read(path.Join(path.Dir(opfPath), href))
+		if err != nil {
+			return nil, err
+		}
+
+		heading, text, err := htmlText(doc)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка разбора %s: %w", href, err)
+		}
+		b.Chapters = appendChapter(b.Chapters, heading, text)
+	}
+
+	return b, nil
+}
+
+// htmlText извлекает из XHTML-документа первый заголовок и остальной
+// текст, разделяя блочные элементы переводами строк.
+func htmlText(doc []byte) (string, string, error) {
+	root, err := html.Parse(bytes.NewReader(doc))
+	if err != nil {
+		return "", "", err
+	}
+
+	var heading, text strings.Builder
+	var walk func(n *html.Node, inHeading bool)
+	walk = func(n *html.Node, inHeading bool) {
+		switch n.Type {
+		case html.TextNode:
+			if inHeading {
+				heading.WriteString(n.Data)
+			} else {
+				text.WriteString(n.Data)
+			}
+			return
+		case html.ElementNode:
+			switch n.Data {
+			case "head", "script", "style", "sup":
+				return
+			case "h1", "h2", "h3":
+				inHeading = inHeading || heading.Len() == 0
+			}
+		}
+
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child, inHeading)
+		}
+
+		if n.Type == html.ElementNode && isBlock(n.Data) {
+			text.WriteByte('\n')
+			if inHeading {
+				heading.WriteByte(' ')
+			}
+		}
+	}
+	walk(root, false)
+
+	return heading.String(), text.String(), nil
+}
+
+func isBlock(tag string) bool {
+	switch tag {
+	case "p", "div", "br", "li", "tr", "blockquote", "section", "h1", "h2", "h3", "h4", "h5", "h6":
+		return true
+	}
+	return false
+}
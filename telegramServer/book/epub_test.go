@@ -0,0 +1,117 @@
+package book
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// newEPUB собирает EPUB из файлов name → содержимое.
+func newEPUB(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const epubContainerXML = `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`
+
+func TestParseEPUBSpineOrder(t *testing.T) {
+	data := newEPUB(t, map[string]string{
+		"META-INF/container.xml": epubContainerXML,
+		"OEBPS/content.opf": `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <metadata><dc:title>Сказки</dc:title></metadata>
+  <manifest>
+    <item id="a" href="text/one.xhtml" media-type="application/xhtml+xml"/>
+    <item id="b" href="text/two%20parts.xhtml" media-type="application/xhtml+xml"/>
+    <item id="css" href="style.css" media-type="text/css"/>
+  </manifest>
+  <spine><itemref idref="b"/><itemref idref="css"/><itemref idref="a"/></spine>
+</package>`,
+		"OEBPS/text/one.xhtml":       `<html><body><h1>Первая</h1><p>Текст первой.</p></body></html>`,
+		"OEBPS/text/two parts.xhtml": `<html><body><h2>Вторая</h2><p>Текст второй.</p><p>Ещё абзац.</p></body></html>`,
+		"OEBPS/style.css":            `p { margin: 0 }`,
+	})
+
+	b, err := Parse("book.epub", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Title != "Сказки" {
+		t.Errorf("название %q, ожидалось %q", b.Title, "Сказки")
+	}
+	want := []Chapter{
+		{Title: "Вторая", Text: "Текст второй.\nЕщё абзац."},
+		{Title: "Первая", Text: "Текст первой."},
+	}
+	assertChapters(t, b.Chapters, want)
+}
+
+func TestParseEPUBTooLarge(t *testing.T) {
+	data := newEPUB(t, map[string]string{
+		"META-INF/container.xml": epubContainerXML,
+		"OEBPS/content.opf": `<package>
+  <manifest><item id="a" href="big.xhtml" media-type="application/xhtml+xml"/></manifest>
+  <spine><itemref idref="a"/></spine>
+</package>`,
+		// Сжимается в десятки килобайт.
+		"OEBPS/big.xhtml": "<p>" + strings.Repeat("а", MaxUnpackedSize) + "</p>",
+	})
+	if len(data) > MaxUnpackedSize/100 {
+		t.Fatalf("архив занимает %d байт, тест проверяет сжатый файл", len(data))
+	}
+
+	_, err := Parse("book.epub", data)
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("ошибка %v, ожидалась %v", err, ErrTooLarge)
+	}
+}
+
+func TestZipBookLimitIsTotal(t *testing.T) {
+	data := newEPUB(t, map[string]string{"a": "12345", "b": "67890"})
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	z := &zipBook{archive: archive, left: 8}
+	if _, err := z.read("a"); err != nil {
+		t.Fatal(err)
+	}
+	// Повторное чтение тоже расходует лимит: spine может ссылаться на
+	// один документ много раз.
+	if _, err := z.read("a"); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("ошибка %v, ожидалась %v", err, ErrTooLarge)
+	}
+}
+
+func assertChapters(t *testing.T, got, want []Chapter) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("глав %d, ожидалось %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("глава %d: %q, ожидалась %q", i+1, got[i], want[i])
+		}
+	}
+}
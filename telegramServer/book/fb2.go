@@ -0,0 +1,142 @@
+package book
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"golang.org/x/text/encoding/htmlindex"
+	"io"
+	"strings"
+)
+
+// parseFB2 читает FictionBook: каждая секция с текстом становится
+// главой. Тела с примечаниями и бинарные данные пропускаются.
+func parseFB2(title string, data []byte) (*Book, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	dec.CharsetReader = charsetReader
+
+	b := &Book{Title: title}
+
+	var (
+		bookTitle    strings.Builder
+		chapterTitle strings.Builder
+		body         strings.Builder
+		// pendingTitle — заголовок секции без собственного текста,
+		// например, "Часть 1", он добавляется к заголовку следующей.
+		pendingTitle string
+
+		inBookTitle bool
+		inBody      bool
+		sections    int
+		titleDepth  int
+	)
+
+	flush := func() {
+		heading := strings.Trim(strings.Join(strings.Fields(chapterTitle.String()), " "), ". ")
+		if pendingTitle != "" && heading != "" {
+			heading = pendingTitle + ". " + heading
+		} else if pendingTitle != "" {
+			heading = pendingTitle
+		}
+
+		if strings.TrimSpace(body.String()) == "" {
+			pendingTitle = heading
+		} else {
+			b.Chapters = appendChapter(b.Chapters, heading, body.String())
+			pendingTitle = ""
+		}
+		chapterTitle.Reset()
+		body.Reset()
+	}
+
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "book-title":
+				inBookTitle = true
+			case "body":
+				inBody = !isNotesBody(t)
+			case "section":
+				if inBody {
+					flush()
+					sections++
+				}
+			case "title":
+				if inBody && sections > 0 {
+					titleDepth++
+				}
+			}
+
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "book-title":
+				inBookTitle = false
+			case "body":
+				if inBody {
+					flush()
+				}
+				inBody = false
+			case "section":
+				if inBody && sections > 0 {
+					flush()
+					sections--
+				}
+			case "title":
+				if titleDepth > 0 {
+					titleDepth--
+				}
+			case "p", "v", "subtitle", "text-author":
+				if titleDepth > 0 {
+					chapterTitle.WriteString(". ")
+				} else if inBody && sections > 0 {
+					body.WriteByte('\n')
+				}
+			}
+
+		case xml.CharData:
+			switch {
+			case inBookTitle:
+				bookTitle.Write(t)
+			case titleDepth > 0:
+				chapterTitle.Write(t)
+			case inBody && sections > 0:
+				body.Write(t)
+			}
+		}
+	}
+
+	if t := strings.Join(strings.Fields(bookTitle.String()), " "); t != "" {
+		b.Title = t
+	}
+	return b, nil
+}
+
+func isNotesBody(el xml.StartElement) bool {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == "name" && (attr.Value == "notes" || attr.Value == "comments") {
+			return true
+		}
+	}
+	return false
+}
+
+// charsetReader позволяет читать XML в кодировках, отличных от UTF-8,
+// например, распространённой в FB2 windows-1251.
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return nil, err
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
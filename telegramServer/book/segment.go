@@ -0,0 +1,108 @@
+package book
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var sentenceEnd = regexp.MustCompile(`[.!?…]+["»”')\]]*\s+`)
+
+// Segments делит текст на куски не длиннее maxLen символов. Границы
+// выбираются по абзацам и предложениям, а слишком длинные предложения
+// делятся по словам.
+func Segments(text string, maxLen int) []string {
+	var (
+		segments []string
+		current  strings.Builder
+		length   int
+	)
+
+	flush := func() {
+		if length > 0 {
+			segments = append(segments, current.String())
+			current.Reset()
+			length = 0
+		}
+	}
+	add := func(piece string, sep string) {
+		n := utf8.RuneCountInString(piece)
+		if length > 0 && length+1+n > maxLen {
+			flush()
+		}
+		if length > 0 {
+			current.WriteString(sep)
+			length++
+		}
+		current.WriteString(piece)
+		length += n
+	}
+
+	for _, paragraph := range strings.Split(text, "\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+
+		sep := "\n"
+		for _, sentence := range sentences(paragraph) {
+			for _, piece := range splitWords(sentence, maxLen) {
+				add(piece, sep)
+				sep = " "
+			}
+		}
+	}
+	flush()
+
+	return segments
+}
+
+func sentences(paragraph string) []string {
+	var result []string
+	start := 0
+	for _, loc := range sentenceEnd.FindAllStringIndex(paragraph, -1) {
+		result = append(result, strings.TrimSpace(paragraph[start:loc[1]]))
+		start = loc[1]
+	}
+	if rest := strings.TrimSpace(paragraph[start:]); rest != "" {
+		result = append(result, rest)
+	}
+	return result
+}
+
+// splitWords делит предложение длиннее maxLen по пробелам, а слова
+// длиннее maxLen — по символам.
+func splitWords(sentence string, maxLen int) []string {
+	if utf8.RuneCountInString(sentence) <= maxLen {
+		return []string{sentence}
+	}
+
+	var (
+		result  []string
+		current []string
+		length  int
+	)
+	for _, word := range strings.Fields(sentence) {
+		runes := []rune(word)
+		n := len(runes)
+		if length > 0 && length+1+n > maxLen {
+			result = append(result, strings.Join(current, " "))
+			current, length = nil, 0
+		}
+		for n > maxLen {
+			result = append(result, string(runes[:maxLen]))
+			runes = runes[maxLen:]
+			n = len(runes)
+		}
+
+		if length > 0 {
+			length++
+		}
+		current = append(current, string(runes))
+		length += n
+	}
+	if len(current) > 0 {
+		result = append(result, strings.Join(current, " "))
+	}
+	return result
+}
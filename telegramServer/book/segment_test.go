@@ -0,0 +1,65 @@
+package book
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSegments(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		maxLen int
+		want   []string
+	}{
+		{
+			name:   "умещается целиком",
+			text:   "Первое. Второе.\n\nТретье.",
+			maxLen: 100,
+			want:   []string{"Первое. Второе.\nТретье."},
+		},
+		{
+			name:   "по предложениям",
+			text:   "Раз два. Три четыре! Пять?",
+			maxLen: 12,
+			want:   []string{"Раз два.", "Три четыре!", "Пять?"},
+		},
+		{
+			name:   "кавычки после точки",
+			text:   "Он сказал: «Привет.» Она ушла.",
+			maxLen: 20,
+			want:   []string{"Он сказал: «Привет.»", "Она ушла."},
+		},
+		{
+			name:   "по словам",
+			text:   "один два три четыре",
+			maxLen: 8,
+			want:   []string{"один два", "три", "четыре"},
+		},
+		{
+			name:   "длинное слово по символам",
+			text:   "абвгдеёжз",
+			maxLen: 4,
+			want:   []string{"абвг", "деёж", "з"},
+		},
+		{
+			name:   "пустой текст",
+			text:   " \n ",
+			maxLen: 10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Segments(tt.text, tt.maxLen)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Segments(%q, %d) = %q, ожидалось %q", tt.text, tt.maxLen, got, tt.want)
+			}
+			for _, segment := range got {
+				if n := utf8.RuneCountInString(segment); n > tt.maxLen {
+					t.Errorf("кусок %q длиной %d", segment, n)
+				}
+			}
+		})
+	}
+}
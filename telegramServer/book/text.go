@@ -0,0 +1,94 @@
+package book
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxHeadingLen — строки длиннее этого не считаются заголовками глав
+// в простом тексте.
+const maxHeadingLen = 80
+
+var (
+	textHeading = regexp.MustCompile(`(?i)^(глава|часть|пролог|эпилог|chapter|part|prologue|epilogue)([\s.:]|$)`)
+
+	mdHeading    = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdFence      = regexp.MustCompile("^\\s*(```|~~~)")
+	mdImage      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink       = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	mdHTML       = regexp.MustCompile(`<[^>]+>`)
+	mdEmphasis   = regexp.MustCompile("[*_~`]+")
+	mdListMarker = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s+`)
+	mdQuote      = regexp.MustCompile(`^\s*(>\s?)+`)
+	mdRule       = regexp.MustCompile(`^\s*([-*_]\s*){3,}$`)
+)
+
+// parseText делит простой текст на главы по строкам вида "Глава 1"
+// или "Chapter 1". Текст до первого заголовка становится отдельной
+// главой без названия.
+func parseText(title string, text string) *Book {
+	b := &Book{Title: title}
+
+	var chapterTitle string
+	var body strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if utf8.RuneCountInString(trimmed) <= maxHeadingLen && textHeading.MatchString(trimmed) {
+			b.Chapters = appendChapter(b.Chapters, chapterTitle, body.String())
+			chapterTitle = trimmed
+			body.Reset()
+			continue
+		}
+		body.WriteString(line)
+		body.WriteByte('\n')
+	}
+	b.Chapters = appendChapter(b.Chapters, chapterTitle, body.String())
+
+	return b
+}
+
+// parseMarkdown делит Markdown на главы по заголовкам первых трёх
+// уровней и убирает разметку, которую не нужно читать вслух.
+func parseMarkdown(title string, text string) *Book {
+	b := &Book{Title: title}
+
+	var chapterTitle string
+	var body strings.Builder
+	inCode := false
+	for _, line := range strings.Split(text, "\n") {
+		if mdFence.MatchString(line) {
+			inCode = !inCode
+			continue
+		}
+		if inCode || mdRule.MatchString(line) {
+			continue
+		}
+
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			heading := stripMarkdown(m[2])
+			if len(m[1]) <= 3 {
+				b.Chapters = appendChapter(b.Chapters, chapterTitle, body.String())
+				chapterTitle = heading
+				body.Reset()
+				continue
+			}
+			line = heading
+		}
+
+		body.WriteString(stripMarkdown(line))
+		body.WriteByte('\n')
+	}
+	b.Chapters = appendChapter(b.Chapters, chapterTitle, body.String())
+
+	return b
+}
+
+func stripMarkdown(line string) string {
+	line = mdQuote.ReplaceAllString(line, "")
+	line = mdListMarker.ReplaceAllString(line, "")
+	line = mdImage.ReplaceAllString(line, "$1")
+	line = mdLink.ReplaceAllString(line, "$1")
+	line = mdHTML.ReplaceAllString(line, "")
+	return mdEmphasis.ReplaceAllString(line, "")
+}
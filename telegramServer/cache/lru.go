@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// LRU — потокобезопасный кэш с ограничением по количеству записей и,
+// если задан sizeOf, по суммарному размеру значений. При переполнении
+// вытесняются записи, к которым дольше всего не обращались.
+type LRU[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	maxSize  int64
+	size     int64
+	sizeOf   func(V) int64
+	order    *list.List
+	items    map[K]*list.Element
+}
+
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+	size  int64
+}
+
+func NewLRU[K comparable, V any](capacity int) *LRU[K, V] {
+	return &LRU[K, V]{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[K]*list.Element),
+	}
+}
+
+// NewSizedLRU создаёт кэш, в котором суммарный размер значений, по
+// оценке sizeOf, не превышает maxSize.
+func NewSizedLRU[K comparable, V any](capacity int, maxSize int64, sizeOf func(V) int64) *LRU[K, V] {
+	c := NewLRU[K, V](capacity)
+	c.maxSize = maxSize
+	c.sizeOf = sizeOf
+	return c
+}
+
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*entry[K, V]).value, true
+}
+
+// Put добавляет значение. Значение больше maxSize не кэшируется.
+func (c *LRU[K, V]) Put(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var size int64
+	if c.sizeOf != nil {
+		size = c.sizeOf(value)
+		if size > c.maxSize {
+			return
+		}
+	}
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[K, V])
+		c.size += size - e.size
+		e.value, e.size = value, size
+		c.order.MoveToFront(el)
+	} else {
+		c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, size: size})
+		c.size += size
+	}
+
+	for c.order.Len() > 0 && (c.capacity > 0 && c.order.Len() > c.capacity || c.sizeOf != nil && c.size > c.maxSize) {
+		c.removeElement(c.order.Back())
+	}
+}
+
+func (c *LRU[K, V]) Remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+// RemoveFunc удаляет все записи, для которых fn возвращает true, и
+// возвращает их количество.
+func (c *LRU[K, V]) RemoveFunc(fn func(key K, value V) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		e := el.Value.(*entry[K, V])
+		if fn(e.key, e.value) {
+			c.removeElement(el)
+			removed++
+		}
+		el = next
+	}
+	return removed
+}
+
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Size возвращает суммарный размер значений по оценке sizeOf.
+func (c *LRU[K, V]) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+func (c *LRU[K, V]) removeElement(el *list.Element) {
+	e := el.Value.(*entry[K, V])
+	c.order.Remove(el)
+	delete(c.items, e.key)
+	c.size -= e.size
+}
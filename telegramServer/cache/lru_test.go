@@ -0,0 +1,73 @@
+package cache
+
+import "testing"
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU[string, int](2)
+	c.Put("a", 1)
+	c.Put("b", 2)
+	// Обращение к a делает самой старой запись b.
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %d, %t", v, ok)
+	}
+	c.Put("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b не вытеснена")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%s вытеснена", key)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, ожидалось 2", c.Len())
+	}
+}
+
+func TestSizedLRU(t *testing.T) {
+	c := NewSizedLRU[string, []byte](10, 10, func(v []byte) int64 { return int64(len(v)) })
+	c.Put("a", make([]byte, 4))
+	c.Put("b", make([]byte, 4))
+	c.Get("a")
+
+	// По числу записей место есть, но суммарный размер превышен:
+	// вытесняется b, к которой дольше не обращались.
+	c.Put("c", make([]byte, 4))
+	if _, ok := c.Get("b"); ok {
+		t.Error("b не вытеснена")
+	}
+	if c.Size() != 8 {
+		t.Errorf("Size() = %d, ожидалось 8", c.Size())
+	}
+
+	// Замена значения учитывает новый размер.
+	c.Put("a", make([]byte, 1))
+	if c.Size() != 5 {
+		t.Errorf("после замены Size() = %d, ожидалось 5", c.Size())
+	}
+
+	// Значение больше лимита не кэшируется и не вытесняет остальные.
+	c.Put("big", make([]byte, 11))
+	if _, ok := c.Get("big"); ok {
+		t.Error("значение больше лимита закэшировано")
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, ожидалось 2", c.Len())
+	}
+}
+
+func TestLRURemove(t *testing.T) {
+	c := NewSizedLRU[int, []byte](0, 100, func(v []byte) int64 { return int64(len(v)) })
+	for i := range 5 {
+		c.Put(i, make([]byte, i))
+	}
+
+	c.Remove(4)
+	if removed := c.RemoveFunc(func(key int, _ []byte) bool { return key%2 == 0 }); removed != 2 {
+		t.Errorf("RemoveFunc удалил %d записей, ожидалось 2", removed)
+	}
+	if c.Len() != 2 || c.Size() != 4 {
+		t.Errorf("Len() = %d, Size() = %d, ожидалось 2 и 4", c.Len(), c.Size())
+	}
+}
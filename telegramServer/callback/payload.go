@@ -0,0 +1,121 @@
+package callback
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Version меняется при несовместимом изменении формата данных кнопок:
+// кнопки со старой версией считаются устаревшими.
+const Version = 2
+
+// MaxDataLen — ограничение Telegram на длину callback_data.
+const MaxDataLen = 64
+
+const macLen = 8
+
+var (
+	ErrStale  = errors.New("callback payload is stale")
+	ErrForged = errors.New("callback payload is forged")
+)
+
+type Action uint8
+
+// Payload — типизированные данные inline-кнопки.
+type Payload struct {
+	Version  uint8
+	Action   Action
+	ModelID  int64
+	Arg      int64
+	IssuedAt time.Time
+}
+
+// Codec кодирует Payload в компактную строку, подписанную HMAC.
+// Подпись включает ID пользователя, поэтому кнопку нельзя
+// переиспользовать от имени другого пользователя.
+type Codec struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func NewCodec(secret string, ttl time.Duration) *Codec {
+	return &Codec{
+		secret: []byte(secret),
+		ttl:    ttl,
+		now:    time.Now,
+	}
+}
+
+func (c *Codec) Encode(userID int64, p Payload) (string, error) {
+	if p.ModelID < 0 || p.Arg < 0 {
+		return "", fmt.Errorf("отрицательные значения в payload не поддерживаются")
+	}
+
+	buf := make([]byte, 0, 2+2*binary.MaxVarintLen64+4+macLen)
+	buf = append(buf, Version, byte(p.Action))
+	buf = binary.AppendUvarint(buf, uint64(p.ModelID))
+	buf = binary.AppendUvarint(buf, uint64(p.Arg))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(c.now().Unix()/60))
+	buf = append(buf, c.sign(userID, buf)...)
+
+	data := base64.RawURLEncoding.EncodeToString(buf)
+	if len(data) > MaxDataLen {
+		return "", fmt.Errorf("callback data длиннее %d байт: %d", MaxDataLen, len(data))
+	}
+	return data, nil
+}
+
+func (c *Codec) Decode(userID int64, data string) (Payload, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil || len(buf) < 2+2+4+macLen {
+		return Payload{}, fmt.Errorf("неизвестный формат данных: %w", ErrStale)
+	}
+	if buf[0] != Version {
+		return Payload{}, fmt.Errorf("версия %d: %w", buf[0], ErrStale)
+	}
+
+	body, mac := buf[:len(buf)-macLen], buf[len(buf)-macLen:]
+	if !hmac.Equal(mac, c.sign(userID, body)) {
+		return Payload{}, ErrForged
+	}
+
+	p := Payload{Version: body[0], Action: Action(body[1])}
+	rest := body[2:]
+
+	modelID, n := binary.Uvarint(rest)
+	if n <= 0 {
+		return Payload{}, ErrForged
+	}
+	rest = rest[n:]
+
+	arg, n := binary.Uvarint(rest)
+	if n <= 0 {
+		return Payload{}, ErrForged
+	}
+	rest = rest[n:]
+
+	if len(rest) != 4 {
+		return Payload{}, ErrForged
+	}
+	p.ModelID = int64(modelID)
+	p.Arg = int64(arg)
+	p.IssuedAt = time.Unix(int64(binary.BigEndian.Uint32(rest))*60, 0)
+
+	if c.ttl > 0 && c.now().Sub(p.IssuedAt) > c.ttl {
+		return Payload{}, fmt.Errorf("выдана %s: %w", p.IssuedAt.Format(time.RFC3339), ErrStale)
+	}
+	return p, nil
+}
+
+func (c *Codec) sign(userID int64, body []byte) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	_ = binary.Write(mac, binary.BigEndian, userID)
+	mac.Write(body)
+	return mac.Sum(nil)[:macLen]
+}
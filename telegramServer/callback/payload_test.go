@@ -0,0 +1,64 @@
+package callback
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	codec := NewCodec("secret", time.Hour)
+	data, err := codec.Encode(42, Payload{Action: 3, ModelID: 1 << 40, Arg: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) > MaxDataLen {
+		t.Fatalf("data длиннее %d: %d", MaxDataLen, len(data))
+	}
+
+	p, err := codec.Decode(42, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Action != 3 || p.ModelID != 1<<40 || p.Arg != 7 {
+		t.Fatalf("Decode = %+v", p)
+	}
+
+	if _, err := codec.Decode(43, data); !errors.Is(err, ErrForged) {
+		t.Fatalf("кнопка другого пользователя: %v, want ErrForged", err)
+	}
+}
+
+func TestCodecStale(t *testing.T) {
+	codec := NewCodec("secret", time.Hour)
+	issued := time.Now()
+	codec.now = func() time.Time { return issued }
+	data, err := codec.Encode(1, Payload{Action: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codec.now = func() time.Time { return issued.Add(2 * time.Hour) }
+	if _, err := codec.Decode(1, data); !errors.Is(err, ErrStale) {
+		t.Fatalf("просроченная кнопка: %v, want ErrStale", err)
+	}
+}
+
+func TestKeyboardErr(t *testing.T) {
+	router := NewRouter(NewCodec("secret", time.Hour), zap.NewNop())
+	kb := router.Keyboard(1)
+
+	if btn := kb.Btn("ok", Payload{Action: 1}); btn.Data == "" {
+		t.Fatal("у кнопки нет данных")
+	}
+	if kb.Err() != nil {
+		t.Fatalf("Err = %v", kb.Err())
+	}
+
+	kb.Btn("bad", Payload{Action: 1, Arg: -1})
+	if kb.Err() == nil {
+		t.Fatal("ошибка кодирования не сохранилась")
+	}
+}
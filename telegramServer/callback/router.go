@@ -0,0 +1,122 @@
+package callback
+
+import (
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"gopkg.in/telebot.v3"
+	"kursach/i18n"
+)
+
+type HandlerFunc func(c telebot.Context, p Payload) error
+
+// Router — единая точка входа для всех inline-кнопок бота. Он
+// регистрируется на telebot.OnCallback, проверяет данные кнопки и
+// передаёт их обработчику, зарегистрированному для Action.
+type Router struct {
+	codec    *Codec
+	handlers map[Action]HandlerFunc
+	log      *zap.Logger
+}
+
+func NewRouter(codec *Codec, logger *zap.Logger) *Router {
+	return &Router{
+		codec:    codec,
+		handlers: make(map[Action]HandlerFunc),
+		log:      logger,
+	}
+}
+
+func (r *Router) Handle(action Action, handler HandlerFunc) {
+	if _, ok := r.handlers[action]; ok {
+		r.log.Panic("Повторная регистрация обработчика callback", zap.Uint8("action", uint8(action)))
+	}
+	r.handlers[action] = handler
+}
+
+// Btn создаёт inline-кнопку с подписанными данными для пользователя.
+func (r *Router) Btn(userID int64, text string, p Payload) (telebot.Btn, error) {
+	data, err := r.codec.Encode(userID, p)
+	if err != nil {
+		return telebot.Btn{}, fmt.Errorf("ошибка кодирования данных кнопки %d: %w", p.Action, err)
+	}
+	return telebot.Btn{Text: text, Data: data}, nil
+}
+
+// Keyboard создаёт кнопки одного сообщения для пользователя. Первая
+// ошибка кодирования запоминается, и Err возвращает её, чтобы
+// сообщение не ушло с нерабочей кнопкой.
+func (r *Router) Keyboard(userID int64) *Keyboard {
+	return &Keyboard{router: r, userID: userID}
+}
+
+type Keyboard struct {
+	router *Router
+	userID int64
+	err    error
+}
+
+func (k *Keyboard) Btn(text string, p Payload) telebot.Btn {
+	btn, err := k.router.Btn(k.userID, text, p)
+	if err != nil && k.err == nil {
+		k.err = err
+	}
+	return btn
+}
+
+func (k *Keyboard) Err() error {
+	return k.err
+}
+
+// OnCallback разбирает данные кнопки и вызывает обработчик. Если
+// обработчик сам не ответил на callback, Router отвечает пустым
+// ответом, чтобы у пользователя пропал индикатор загрузки.
+func (r *Router) OnCallback(c telebot.Context) error {
+	userID := c.Sender().ID
+	lang := i18n.Lang(c)
+	rc := &respondContext{Context: c}
+
+	p, err := r.codec.Decode(userID, c.Callback().Data)
+	switch {
+	case errors.Is(err, ErrForged):
+		r.log.Warn("Отклонены поддельные данные кнопки", zap.Int64("userID", userID), zap.String("data", c.Callback().Data))
+		return c.Respond(&telebot.CallbackResponse{Text: i18n.T(lang, "callback.forged")})
+	case err != nil:
+		r.log.Info("Нажата устаревшая кнопка", zap.Int64("userID", userID), zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: i18n.T(lang, "callback.stale")})
+	}
+
+	handler, ok := r.handlers[p.Action]
+	if !ok {
+		r.log.Warn("Нет обработчика для callback", zap.Uint8("action", uint8(p.Action)))
+		return c.Respond(&telebot.CallbackResponse{Text: i18n.T(lang, "callback.stale")})
+	}
+
+	err = handler(rc, p)
+	if !rc.responded {
+		if err != nil {
+			_ = c.Respond(&telebot.CallbackResponse{Text: i18n.T(lang, "error.generic")})
+		} else {
+			_ = c.Respond()
+		}
+	}
+	return err
+}
+
+type respondContext struct {
+	telebot.Context
+	responded bool
+}
+
+func (c *respondContext) Respond(resp ...*telebot.CallbackResponse) error {
+	c.responded = true
+	return c.Context.Respond(resp...)
+}
+
+func (c *respondContext) RespondText(text string) error {
+	return c.Respond(&telebot.CallbackResponse{Text: text})
+}
+
+func (c *respondContext) RespondAlert(text string) error {
+	return c.Respond(&telebot.CallbackResponse{Text: text, ShowAlert: true})
+}
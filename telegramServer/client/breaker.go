@@ -0,0 +1,78 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker — автомат защиты AudioProcessor. После threshold неудачных
+// запросов подряд он отклоняет запросы в течение cooldown, не нагружая
+// сервер, который загружает модель или перезапускается. Затем
+// пропускается один пробный запрос: при успехе автомат закрывается,
+// при ошибке снова открывается на cooldown.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// allow сообщает, можно ли выполнить запрос.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+}
+
+// abandon учитывает запрос, который отменил вызывающий: счётчик
+// ошибок не меняется, но, если это был пробный запрос, следующий
+// запрос может стать пробным.
+func (b *breaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// failure учитывает неудачный запрос и возвращает true, если автомат
+// открылся.
+func (b *breaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.failures < b.threshold {
+		return false
+	}
+	b.openUntil = b.now().Add(b.cooldown)
+	return true
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.failure()
+	if !b.failure() {
+		t.Fatal("автомат не открылся после двух ошибок")
+	}
+	if b.allow() {
+		t.Fatal("открытый автомат пропустил запрос")
+	}
+
+	now = now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatal("после паузы не пропущен пробный запрос")
+	}
+	if b.allow() {
+		t.Fatal("пропущен второй пробный запрос")
+	}
+
+	// Отменённый пробный запрос не закрывает автомат, но позволяет
+	// сделать новую пробу.
+	b.abandon()
+	if !b.allow() {
+		t.Fatal("после отмены пробы не пропущен новый пробный запрос")
+	}
+	b.success()
+	if !b.allow() || !b.allow() {
+		t.Fatal("автомат не закрылся после успешной пробы")
+	}
+}
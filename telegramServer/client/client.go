@@ -2,48 +2,240 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"time"
+	"unicode/utf8"
 
+	"kursach/defs"
 	pb "kursach/proto"
 
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-type Client struct {
-	conn *grpc.ClientConn
-}
+const (
+	// Повторы запросов при временных ошибках: пауза растёт от
+	// retryBaseDelay вдвое с каждой попыткой, до retryMaxDelay, и
+	// выбирается случайно, чтобы запросы не повторялись одновременно.
+	maxAttempts    = 3
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 5 * time.Second
+
+	// Дедлайн синтеза растёт с длиной текста: XTTS озвучивает текст
+	// примерно за время, пропорциональное его длине.
+	synthesisBaseTimeout = 30 * time.Second
+	synthesisRuneTimeout = 100 * time.Millisecond
+	synthesisMaxTimeout  = 5 * time.Minute
+
+	// Дедлайн распознавания растёт с размером файла.
+	transcribeBaseTimeout = 30 * time.Second
+	transcribeMBTimeout   = 30 * time.Second
+	transcribeMaxTimeout  = 5 * time.Minute
 
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
+)
+
+// AudioProcessorClient отправляет запросы на пул серверов
+// AudioProcessor. Соединения устанавливаются в фоне и восстанавливаются
+// после обрывов, поэтому бот запускается, даже если серверы ещё
+// загружают модель.
 type AudioProcessorClient struct {
-	conn *grpc.ClientConn
+	pool *pool
+	log  *zap.Logger
 }
 
-func NewAudioProcessorClient(host, port string) (*AudioProcessorClient, error) {
-	address := fmt.Sprintf("%s:%s", host, port)
-	conn, err := grpc.Dial(address, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(5*time.Second))
+// NewAudioProcessorClient создаёт клиент. opts дополняют параметры
+// соединений, например, подменяют транспорт в тестах.
+func NewAudioProcessorClient(endpoints []Endpoint, security Security, logger *zap.Logger, opts ...grpc.DialOption) (*AudioProcessorClient, error) {
+	p, err := newPool(endpoints, security, logger, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("не удалось подключиться к gRPC серверу: %w", err)
+		return nil, err
 	}
+	return &AudioProcessorClient{pool: p, log: logger}, nil
+}
 
-	return &AudioProcessorClient{conn: conn}, nil
+// Backends возвращает состояние серверов пула.
+func (a *AudioProcessorClient) Backends() []BackendStatus {
+	return a.pool.status()
 }
 
-func (a *AudioProcessorClient) SendAudio(text string, audioData []byte) (*pb.ProcessingResponse, error) {
-	client := pb.NewAudioProcessorClient(a.conn)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*100)
-	defer cancel()
+// Drain перестаёт отправлять новые запросы на сервер address, например,
+// перед его обновлением, и ждёт завершения уже выполняемых.
+func (a *AudioProcessorClient) Drain(ctx context.Context, address string) error {
+	return a.pool.drain(ctx, address)
+}
+
+// Resume возвращает сервер address в балансировку после Drain.
+func (a *AudioProcessorClient) Resume(address string) error {
+	return a.pool.resume(address)
+}
 
+func (a *AudioProcessorClient) Close() {
+	a.pool.close()
+}
+
+// SendAudio озвучивает text голосом из audioData. speed — темп речи,
+// 1 — обычный. ID запроса из ctx (см. WithRequestID) передаётся серверу.
+func (a *AudioProcessorClient) SendAudio(ctx context.Context, text string, audioData []byte, speed float64) (*pb.ProcessingResponse, error) {
 	req := &pb.ContentRequest{
 		Text: text,
 		Audio: &pb.AudioFile{
 			Data: audioData,
 		},
+		Speed: float32(speed),
+	}
+
+	timeout := scaledTimeout(synthesisBaseTimeout, synthesisRuneTimeout, utf8.RuneCountInString(text), synthesisMaxTimeout)
+
+	var resp *pb.ProcessingResponse
+	err := a.call(ctx, "ProcessContent", timeout, func(ctx context.Context, client pb.AudioProcessorClient) error {
+		var err error
+		resp, err = client.ProcessContent(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при отправке: %w", err)
+	}
+	if resp.GetStatus() != "OK" {
+		rpcErrors.WithLabelValues("ProcessContent", codeStatusError).Inc()
+	}
+
+	return resp, nil
+}
+
+// Transcribe распознаёт речь. language — подсказка языка речи, пустая
+// строка означает автоматическое определение.
+func (a *AudioProcessorClient) Transcribe(ctx context.Context, audioData []byte, language string) (*pb.TranscribeResponse, error) {
+	req := &pb.TranscribeRequest{
+		Audio: &pb.AudioFile{
+			Data: audioData,
+		},
+		Language: language,
 	}
 
-	resp, err := client.ProcessContent(ctx, req)
+	timeout := scaledTimeout(transcribeBaseTimeout, transcribeMBTimeout, len(audioData)>>20, transcribeMaxTimeout)
+
+	var resp *pb.TranscribeResponse
+	err := a.call(ctx, "Transcribe", timeout, func(ctx context.Context, client pb.AudioProcessorClient) error {
+		var err error
+		resp, err = client.Transcribe(ctx, req)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("ошибка при отправке: %v", err)
+		return nil, fmt.Errorf("ошибка при распознавании: %w", err)
+	}
+	if resp.GetStatus() != "OK" {
+		rpcErrors.WithLabelValues("Transcribe", codeStatusError).Inc()
 	}
 
 	return resp, nil
 }
+
+// call выполняет запрос на свободном сервере пула с дедлайном timeout.
+// При временной ошибке запрос повторяется, по возможности на другом
+// сервере. Если исправных серверов нет, возвращается
+// defs.ErrServiceBusy. Отмена parent прерывает запрос и повторы.
+func (a *AudioProcessorClient) call(parent context.Context, method string, timeout time.Duration, fn func(ctx context.Context, client pb.AudioProcessorClient) error) error {
+	// Повторы идут с тем же ID, что и первая попытка.
+	requestID, ok := RequestID(parent)
+	if !ok {
+		requestID = newRequestID()
+		parent = WithRequestID(parent, requestID)
+	}
+
+	var (
+		failed *backend
+		err    error
+	)
+	for attempt := 1; ; attempt++ {
+		b, acquireErr := a.pool.acquire(parent, failed)
+		if acquireErr != nil {
+			if errors.As(acquireErr, &defs.ErrServiceBusy{}) {
+				rpcErrors.WithLabelValues(method, codeNoBackend).Inc()
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %v: %w", method, err, acquireErr)
+			}
+			return fmt.Errorf("%s отклонён: %w", method, acquireErr)
+		}
+
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		err = fn(ctx, b.client)
+		cancel()
+		a.pool.release(b)
+
+		switch {
+		case err != nil && parent.Err() != nil:
+			// Запрос отменил вызывающий, например, пользователь ушёл или
+			// бот останавливается: о состоянии сервера это не говорит.
+			b.breaker.abandon()
+			return err
+		case err == nil || !unhealthy(err):
+			b.breaker.success()
+			return err
+		case b.breaker.failure():
+			a.log.Error("Сервер AudioProcessor не отвечает, запросы временно не отправляются",
+				zap.String("address", b.address),
+				zap.Duration("cooldown", breakerCooldown),
+				zap.Error(err),
+			)
+		}
+
+		if !retryable(err) || attempt == maxAttempts {
+			return fmt.Errorf("%s: %v: %w", method, err, defs.ErrServiceBusy{})
+		}
+
+		failed = b
+		delay := retryDelay(attempt)
+		a.log.Warn("Повтор запроса к AudioProcessor",
+			zap.String("requestID", requestID),
+			zap.String("method", method),
+			zap.String("address", b.address),
+			zap.Int("attempt", attempt),
+			zap.Duration("delay", delay),
+			zap.Error(err),
+		)
+		select {
+		case <-time.After(delay):
+		case <-parent.Done():
+			return fmt.Errorf("%s: %w", method, parent.Err())
+		}
+	}
+}
+
+// retryable сообщает, что запрос не дошёл до обработки и его можно
+// безопасно повторить.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// unhealthy сообщает, что ошибка говорит о недоступности сервера, а не
+// о проблеме конкретного запроса.
+func unhealthy(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+func retryDelay(attempt int) time.Duration {
+	ceiling := min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+	return ceiling/2 + rand.N(ceiling/2)
+}
+
+func scaledTimeout(base time.Duration, perUnit time.Duration, units int, ceiling time.Duration) time.Duration {
+	return min(base+time.Duration(units)*perUnit, ceiling)
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"kursach/audioserver"
+	"kursach/defs"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestEndToEnd(t *testing.T) {
+	server := startServer(t, serverConfig{Options: audioserver.Options{Transcript: "привет"}})
+	c := newTestClient(t, server.Addr, Security{})
+	ctx := context.Background()
+
+	resp, err := c.SendAudio(ctx, "привет, мир", []byte("образец"), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetStatus() != "OK" || !bytes.HasPrefix(resp.GetResult().GetProcessedAudio(), []byte("RIFF")) {
+		t.Fatalf("синтез: статус %q, %d байт", resp.GetStatus(), len(resp.GetResult().GetProcessedAudio()))
+	}
+
+	transcript, err := c.Transcribe(ctx, []byte("audio"), "ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transcript.GetStatus() != "OK" || transcript.GetText() != "привет" {
+		t.Fatalf("распознавание: статус %q, текст %q", transcript.GetStatus(), transcript.GetText())
+	}
+
+	backends := c.Backends()
+	if len(backends) != 1 || !backends[0].Healthy || backends[0].InFlight != 0 {
+		t.Fatalf("состояние бэкендов: %+v", backends)
+	}
+
+	// После Shutdown сервер отвечает NOT_SERVING, и пул перестаёт
+	// отправлять ему запросы при следующей проверке здоровья.
+	server.Shutdown()
+	deadline := time.Now().Add(healthInterval + healthTimeout)
+	for c.Backends()[0].Healthy {
+		if time.Now().After(deadline) {
+			t.Fatal("бэкенд в состоянии NOT_SERVING считается исправным")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestFailureInjection(t *testing.T) {
+	t.Run("статус ERROR", func(t *testing.T) {
+		server := startServer(t, serverConfig{Options: audioserver.Options{ErrorRate: 1}})
+		c := newTestClient(t, server.Addr, Security{})
+
+		resp, err := c.SendAudio(context.Background(), "текст", []byte("образец"), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(resp.GetStatus(), "ERROR") {
+			t.Fatalf("статус %q, ожидалась ошибка модели", resp.GetStatus())
+		}
+	})
+
+	t.Run("ошибка запроса", func(t *testing.T) {
+		server := startServer(t, serverConfig{Options: audioserver.Options{FailureRate: 1, FailureCode: codes.InvalidArgument}})
+		c := newTestClient(t, server.Addr, Security{})
+
+		// Ошибка самого запроса не повторяется и возвращается как есть.
+		_, err := c.Transcribe(context.Background(), []byte("audio"), "")
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("ошибка %v, ожидался код %s", err, codes.InvalidArgument)
+		}
+	})
+
+	t.Run("сервер недоступен", func(t *testing.T) {
+		server := startServer(t, serverConfig{Options: audioserver.Options{FailureRate: 1, FailureCode: codes.Unavailable}})
+		c := newTestClient(t, server.Addr, Security{})
+
+		// Временная ошибка повторяется, после последней попытки бот
+		// сообщает, что сервис занят.
+		_, err := c.Transcribe(context.Background(), []byte("audio"), "")
+		if !errors.As(err, &defs.ErrServiceBusy{}) {
+			t.Fatalf("ошибка %v, ожидалась %T", err, defs.ErrServiceBusy{})
+		}
+		if !strings.Contains(err.Error(), "тестовый сбой") {
+			t.Fatalf("в ошибке %q нет причины сбоя", err)
+		}
+	})
+}
+
+func TestCallerCancel(t *testing.T) {
+	server := startServer(t, serverConfig{Options: audioserver.Options{Latency: time.Minute}})
+	c := newTestClient(t, server.Addr, Security{})
+
+	// Отмена запроса вызывающим не считается сбоем сервера.
+	for range breakerThreshold {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err := c.Transcribe(ctx, []byte("audio"), "")
+		cancel()
+		if status.Code(err) != codes.DeadlineExceeded {
+			t.Fatalf("ошибка %v, ожидался код %s", err, codes.DeadlineExceeded)
+		}
+	}
+	if b := c.Backends()[0]; !b.Healthy {
+		t.Fatalf("бэкенд исключён после отмен: %+v", b)
+	}
+	if !c.pool.backends[0].breaker.allow() {
+		t.Fatal("автомат открылся после отмен запросов вызывающим")
+	}
+}
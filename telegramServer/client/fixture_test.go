@@ -0,0 +1,174 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kursach/audioserver"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// testPKI — CA и выпущенные им сертификаты сервера и клиента. Файлы
+// клиента лежат во временном каталоге теста, как при настройке бота
+// через GRPC_TLS_*.
+type testPKI struct {
+	CAFile     string
+	ClientCert string
+	ClientKey  string
+
+	ca     *x509.CertPool
+	server tls.Certificate
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issue := func(serial int64, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "localhost"},
+			DNSNames:     []string{"localhost"},
+			IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	}
+
+	pki := &testPKI{ca: x509.NewCertPool()}
+	pki.ca.AddCert(ca)
+
+	serverCert, serverKey := issue(2, x509.ExtKeyUsageServerAuth)
+	pki.server, err = tls.X509KeyPair(serverCert, serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientCert, clientKey := issue(3, x509.ExtKeyUsageClientAuth)
+	dir := t.TempDir()
+	pki.CAFile = writeFile(t, dir, "ca.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}))
+	pki.ClientCert = writeFile(t, dir, "client.pem", clientCert)
+	pki.ClientKey = writeFile(t, dir, "client.key", clientKey)
+	return pki
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// serverConfig настраивает эталонный AudioProcessor так же, как
+// переменные окружения cmd/audioserver.
+type serverConfig struct {
+	Options audioserver.Options
+	// PKI включает TLS, MutualTLS дополнительно требует сертификат
+	// клиента, Token — заголовок authorization.
+	PKI       *testPKI
+	MutualTLS bool
+	Token     string
+}
+
+type testServer struct {
+	*audioserver.Server
+	Addr string
+}
+
+// startServer запускает audioserver на свободном порту и
+// останавливает его в конце теста.
+func startServer(t *testing.T, config serverConfig) *testServer {
+	t.Helper()
+
+	server := audioserver.New(config.Options, zap.NewNop())
+	interceptors := []grpc.UnaryServerInterceptor{server.LogRequests}
+	var opts []grpc.ServerOption
+	if config.PKI != nil {
+		tlsConfig := &tls.Config{Certificates: []tls.Certificate{config.PKI.server}, MinVersion: tls.VersionTLS12}
+		if config.MutualTLS {
+			tlsConfig.ClientCAs = config.PKI.ca
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	if config.Token != "" {
+		interceptors = append([]grpc.UnaryServerInterceptor{audioserver.CheckToken(config.Token)}, interceptors...)
+	}
+	opts = append(opts, grpc.ChainUnaryInterceptor(interceptors...))
+
+	srv := grpc.NewServer(opts...)
+	server.Register(srv)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	return &testServer{Server: server, Addr: lis.Addr().String()}
+}
+
+func newTestClient(t *testing.T, addr string, security Security) *AudioProcessorClient {
+	t.Helper()
+
+	c, err := NewAudioProcessorClient([]Endpoint{{Address: addr, MaxConcurrent: 2}}, security, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
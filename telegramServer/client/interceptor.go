@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDHeader — заголовок, в котором AudioProcessor получает ID
+// запроса и пишет его в свои логи.
+const RequestIDHeader = "x-request-id"
+
+type requestIDKey struct{}
+
+// WithRequestID добавляет к контексту ID запроса, по которому запрос к
+// AudioProcessor можно найти в логах бота и сервера.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID возвращает ID запроса из контекста.
+func RequestID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// interceptors логируют запросы к AudioProcessor, измеряют их
+// длительность и передают серверу ID запроса. Запрос без ID, например,
+// проверка здоровья, получает случайный.
+type interceptors struct {
+	log *zap.Logger
+}
+
+func (i interceptors) unary(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx, id := outgoingRequestID(ctx)
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	i.observe(method, cc.Target(), id, time.Since(start), err)
+	return err
+}
+
+func (i interceptors) stream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx, id := outgoingRequestID(ctx)
+	start := time.Now()
+	s, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		i.observe(method, cc.Target(), id, time.Since(start), err)
+		return nil, err
+	}
+	return &observedStream{ClientStream: s, done: func(err error) {
+		i.observe(method, cc.Target(), id, time.Since(start), err)
+	}}, nil
+}
+
+func (i interceptors) observe(method string, target string, id string, elapsed time.Duration, err error) {
+	code := status.Code(err)
+	rpcDuration.WithLabelValues(method, target, code.String()).Observe(elapsed.Seconds())
+
+	// Проверки здоровья идут каждые несколько секунд и в логи не
+	// пишутся.
+	if strings.HasPrefix(method, "/grpc.health.") {
+		return
+	}
+	if err != nil {
+		rpcErrors.WithLabelValues(path.Base(method), code.String()).Inc()
+	}
+
+	fields := []zap.Field{
+		zap.String("requestID", id),
+		zap.String("method", method),
+		zap.String("backend", target),
+		zap.Duration("latency", elapsed),
+		zap.String("code", code.String()),
+	}
+	if err != nil {
+		i.log.Warn("Запрос к AudioProcessor завершился ошибкой", append(fields, zap.Error(err))...)
+		return
+	}
+	i.log.Info("Запрос к AudioProcessor выполнен", fields...)
+}
+
+// observedStream вызывает done один раз, когда поток завершается:
+// сервер закрыл его или вернул ошибку.
+type observedStream struct {
+	grpc.ClientStream
+	once sync.Once
+	done func(err error)
+}
+
+func (s *observedStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		result := err
+		if errors.Is(err, io.EOF) {
+			result = nil
+		}
+		s.once.Do(func() { s.done(result) })
+	}
+	return err
+}
+
+func outgoingRequestID(ctx context.Context) (context.Context, string) {
+	id, ok := RequestID(ctx)
+	if !ok {
+		id = newRequestID()
+	}
+	return metadata.AppendToOutgoingContext(ctx, RequestIDHeader, id), id
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
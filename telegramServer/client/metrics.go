@@ -0,0 +1,30 @@
+package client
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Коды ошибок AudioProcessor, которых нет среди кодов gRPC.
+const (
+	// codeNoBackend — запрос не отправлен: нет исправного сервера.
+	codeNoBackend = "NoBackend"
+	// codeStatusError — сервер ответил, но со статусом "ERROR", например,
+	// при ошибке модели.
+	codeStatusError = "StatusError"
+)
+
+var (
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "audio_processor_rpc_duration_seconds",
+		Help: "Длительность запросов к AudioProcessor.",
+		// Синтез длинного текста занимает минуты.
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 14),
+	}, []string{"method", "backend", "code"})
+
+	rpcErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "audio_processor_errors_total",
+		Help: "Ошибки AudioProcessor по кодам: коды gRPC каждой попытки, NoBackend и StatusError.",
+	}, []string{"method", "code"})
+)
+
+func init() {
+	prometheus.MustRegister(rpcDuration, rpcErrors)
+}
@@ -0,0 +1,415 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kursach/defs"
+	pb "kursach/proto"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	healthInterval = 5 * time.Second
+	healthTimeout  = 3 * time.Second
+
+	// acquireTimeout — сколько запрос ждёт свободного места на
+	// бэкендах, прежде чем пользователь получит ответ "сервис занят".
+	acquireTimeout = 2 * time.Minute
+)
+
+// Endpoint — адрес AudioProcessor и число запросов, которые он
+// выполняет одновременно.
+type Endpoint struct {
+	Address       string
+	MaxConcurrent int
+}
+
+// ParseEndpoints разбирает список адресов через запятую. У адреса можно
+// указать свой лимит одновременных запросов: "tts-2:50051=2". Без него
+// используется defaultLimit.
+func ParseEndpoints(list string, defaultLimit int) ([]Endpoint, error) {
+	var endpoints []Endpoint
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		endpoint := Endpoint{Address: item, MaxConcurrent: defaultLimit}
+		if address, limit, ok := strings.Cut(item, "="); ok {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("некорректный лимит запросов у %s", item)
+			}
+			endpoint = Endpoint{Address: address, MaxConcurrent: n}
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	if len(endpoints) == 0 {
+		return nil, errors.New("не указан ни один адрес AudioProcessor")
+	}
+	return endpoints, nil
+}
+
+// BackendStatus — состояние бэкенда для мониторинга.
+type BackendStatus struct {
+	Address       string `json:"address"`
+	Healthy       bool   `json:"healthy"`
+	Draining      bool   `json:"draining"`
+	InFlight      int    `json:"in_flight"`
+	MaxConcurrent int    `json:"max_concurrent"`
+}
+
+type backend struct {
+	address string
+	limit   int
+	conn    *grpc.ClientConn
+	client  pb.AudioProcessorClient
+	health  healthpb.HealthClient
+	breaker *breaker
+
+	// Поля ниже защищены мьютексом пула.
+	healthy  bool
+	draining bool
+	inFlight int
+}
+
+// pool распределяет запросы между бэкендами: выбирается здоровый
+// бэкенд с наименьшим числом выполняемых запросов, у которого не
+// исчерпан лимит. Если все бэкенды заняты, запрос ждёт освобождения.
+type pool struct {
+	log *zap.Logger
+
+	mu       sync.Mutex
+	backends []*backend
+	next     int
+	// changed закрывается и заменяется при каждом изменении состояния
+	// бэкендов, чтобы разбудить ожидающие запросы.
+	changed chan struct{}
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newPool(endpoints []Endpoint, security Security, logger *zap.Logger, extra ...grpc.DialOption) (*pool, error) {
+	opts, err := security.dialOptions()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка настройки защиты канала: %w", err)
+	}
+	opts = append(opts, extra...)
+	opts = append(opts, grpc.WithConnectParams(grpc.ConnectParams{
+		Backoff: backoff.Config{
+			BaseDelay:  time.Second,
+			Multiplier: 1.6,
+			Jitter:     0.2,
+			MaxDelay:   30 * time.Second,
+		},
+		MinConnectTimeout: 10 * time.Second,
+	}))
+	i := interceptors{log: logger}
+	opts = append(opts,
+		grpc.WithChainUnaryInterceptor(i.unary),
+		grpc.WithChainStreamInterceptor(i.stream),
+		// Спаны запросов с заголовком traceparent. Проверки здоровья
+		// идут вне трейсов обновлений и не записываются.
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler(otelgrpc.WithFilter(filters.Not(filters.HealthCheck())))),
+	)
+
+	p := &pool{
+		log:     logger,
+		changed: make(chan struct{}),
+		stop:    make(chan struct{}),
+	}
+
+	for _, endpoint := range endpoints {
+		conn, err := grpc.NewClient(endpoint.Address, opts...)
+		if err != nil {
+			p.close()
+			return nil, fmt.Errorf("не удалось создать gRPC клиент для %s: %w", endpoint.Address, err)
+		}
+		conn.Connect()
+
+		p.backends = append(p.backends, newBackend(endpoint, conn))
+	}
+
+	for _, b := range p.backends {
+		p.wg.Add(1)
+		go p.watchHealth(b)
+	}
+	return p, nil
+}
+
+func newBackend(endpoint Endpoint, conn *grpc.ClientConn) *backend {
+	return &backend{
+		address: endpoint.Address,
+		limit:   endpoint.MaxConcurrent,
+		conn:    conn,
+		client:  pb.NewAudioProcessorClient(conn),
+		health:  healthpb.NewHealthClient(conn),
+		breaker: newBreaker(breakerThreshold, breakerCooldown),
+		// До первой неудачной проверки бэкенд считается исправным,
+		// иначе запросы сразу после запуска отклонялись бы как
+		// «сервис занят».
+		healthy: true,
+	}
+}
+
+// acquire занимает место на бэкенде. Бэкенд avoid, на котором запрос
+// только что не удался, выбирается, только если других нет.
+func (p *pool) acquire(ctx context.Context, avoid *backend) (*backend, error) {
+	timer := time.NewTimer(acquireTimeout)
+	defer timer.Stop()
+
+	for {
+		p.mu.Lock()
+		b, wait := p.pick(avoid)
+		if b != nil {
+			b.inFlight++
+			p.mu.Unlock()
+			return b, nil
+		}
+		changed := p.changed
+		p.mu.Unlock()
+
+		if !wait {
+			return nil, defs.ErrServiceBusy{}
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return nil, fmt.Errorf("нет свободных бэкендов за %s: %w", acquireTimeout, defs.ErrServiceBusy{})
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-p.stop:
+			return nil, defs.ErrServiceBusy{}
+		}
+	}
+}
+
+func (p *pool) release(b *backend) {
+	p.mu.Lock()
+	b.inFlight--
+	p.notifyLocked()
+	p.mu.Unlock()
+}
+
+// pick выбирает бэкенд под мьютексом. Если свободного нет, wait
+// сообщает, есть ли смысл ждать: хотя бы один бэкенд исправен, но
+// занят.
+func (p *pool) pick(avoid *backend) (*backend, bool) {
+	n := len(p.backends)
+	candidates := make([]*backend, 0, n)
+	wait := false
+	for i := 0; i < n; i++ {
+		// Обход начинается с разных бэкендов, чтобы при равной
+		// нагрузке запросы распределялись по кругу.
+		b := p.backends[(p.next+i)%n]
+		if !b.healthy || b.draining {
+			continue
+		}
+		if b.inFlight >= b.limit {
+			wait = true
+			continue
+		}
+		candidates = append(candidates, b)
+	}
+	p.next++
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return better(candidates[i], candidates[j], avoid)
+	})
+	for _, b := range candidates {
+		if b.breaker.allow() {
+			return b, wait
+		}
+	}
+	return nil, wait
+}
+
+func better(b, best, avoid *backend) bool {
+	if (b == avoid) != (best == avoid) {
+		return best == avoid
+	}
+	return b.inFlight < best.inFlight
+}
+
+// drain перестаёт отправлять новые запросы на бэкенд address и ждёт
+// завершения уже выполняемых.
+func (p *pool) drain(ctx context.Context, address string) error {
+	b, err := p.find(address)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	b.draining = true
+	p.notifyLocked()
+	p.mu.Unlock()
+	p.log.Info("Бэкенд выводится из балансировки", zap.String("address", address))
+
+	for {
+		p.mu.Lock()
+		inFlight := b.inFlight
+		changed := p.changed
+		p.mu.Unlock()
+
+		if inFlight == 0 {
+			p.log.Info("Бэкенд выведен из балансировки", zap.String("address", address))
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// resume возвращает бэкенд в балансировку.
+func (p *pool) resume(address string) error {
+	b, err := p.find(address)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	b.draining = false
+	p.notifyLocked()
+	p.mu.Unlock()
+	p.log.Info("Бэкенд возвращён в балансировку", zap.String("address", address))
+	return nil
+}
+
+func (p *pool) find(address string) (*backend, error) {
+	for _, b := range p.backends {
+		if b.address == address {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("бэкенд %s не найден", address)
+}
+
+func (p *pool) status() []BackendStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result := make([]BackendStatus, 0, len(p.backends))
+	for _, b := range p.backends {
+		result = append(result, BackendStatus{
+			Address:       b.address,
+			Healthy:       b.healthy,
+			Draining:      b.draining,
+			InFlight:      b.inFlight,
+			MaxConcurrent: b.limit,
+		})
+	}
+	return result
+}
+
+// watchHealth периодически опрашивает бэкенд по протоколу
+// grpc.health.v1. Бэкенд в состоянии NOT_SERVING, например, при
+// остановке, не получает новых запросов, но дорабатывает текущие.
+func (p *pool) watchHealth(b *backend) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+	for {
+		p.probe(b)
+
+		select {
+		case <-ticker.C:
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// probe проверяет здоровье бэкенда и обновляет его состояние.
+func (p *pool) probe(b *backend) {
+	err := p.checkHealth(b)
+	// pythonServer отвечает на проверки здоровья в том же пуле потоков,
+	// что и на синтез, и под нагрузкой проверка не успевает. Пока на
+	// бэкенде выполняются запросы, таймаут проверки означает, что он
+	// занят, а не сломан: зависший сервер выдадут таймауты самих
+	// запросов и автомат.
+	if status.Code(err) == codes.DeadlineExceeded && p.busy(b) {
+		p.log.Debug("Проверка здоровья не успела, бэкенд занят", zap.String("address", b.address))
+		return
+	}
+	p.setHealthy(b, err == nil)
+}
+
+func (p *pool) busy(b *backend) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return b.inFlight > 0
+}
+
+// checkHealth возвращает nil, если бэкенд готов принимать запросы.
+func (p *pool) checkHealth(b *backend) error {
+	ctx, cancel := context.WithTimeout(context.Background(), healthTimeout)
+	defer cancel()
+
+	resp, err := b.health.Check(ctx, &healthpb.HealthCheckRequest{Service: pb.AudioProcessor_ServiceDesc.ServiceName})
+	// Сервер без health-сервиса считается исправным, если отвечает.
+	if status.Code(err) == codes.Unimplemented {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("бэкенд в состоянии %s", resp.GetStatus())
+	}
+	return nil
+}
+
+func (p *pool) setHealthy(b *backend, healthy bool) {
+	p.mu.Lock()
+	changed := b.healthy != healthy
+	b.healthy = healthy
+	if changed {
+		p.notifyLocked()
+	}
+	p.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if healthy {
+		b.breaker.success()
+		p.log.Info("Бэкенд AudioProcessor доступен", zap.String("address", b.address))
+	} else {
+		p.log.Warn("Бэкенд AudioProcessor недоступен", zap.String("address", b.address))
+	}
+}
+
+func (p *pool) notifyLocked() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+func (p *pool) close() {
+	close(p.stop)
+	p.wg.Wait()
+	for _, b := range p.backends {
+		_ = b.conn.Close()
+	}
+}
@@ -0,0 +1,207 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"kursach/defs"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// fakeHealth отвечает на проверки здоровья заданным статусом или
+// ошибкой.
+type fakeHealth struct {
+	healthpb.HealthClient
+
+	mu     sync.Mutex
+	status healthpb.HealthCheckResponse_ServingStatus
+	err    error
+}
+
+func (f *fakeHealth) Check(ctx context.Context, in *healthpb.HealthCheckRequest, opts ...grpc.CallOption) (*healthpb.HealthCheckResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &healthpb.HealthCheckResponse{Status: f.status}, nil
+}
+
+func (f *fakeHealth) set(status healthpb.HealthCheckResponse_ServingStatus, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status, f.err = status, err
+}
+
+// newTestPool собирает пул без соединений и проверок здоровья в фоне:
+// по бэкенду на каждый лимит.
+func newTestPool(limits ...int) (*pool, []*fakeHealth) {
+	p := &pool{
+		log:     zap.NewNop(),
+		changed: make(chan struct{}),
+		stop:    make(chan struct{}),
+	}
+	var health []*fakeHealth
+	for i, limit := range limits {
+		b := newBackend(Endpoint{Address: string(rune('a' + i)), MaxConcurrent: limit}, nil)
+		h := &fakeHealth{status: healthpb.HealthCheckResponse_SERVING}
+		b.health = h
+		p.backends = append(p.backends, b)
+		health = append(health, h)
+	}
+	return p, health
+}
+
+func mustAcquire(t *testing.T, p *pool, avoid *backend) *backend {
+	t.Helper()
+	b, err := p.acquire(context.Background(), avoid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestPoolLeastInFlight(t *testing.T) {
+	p, _ := newTestPool(3, 3)
+
+	first := mustAcquire(t, p, nil)
+	second := mustAcquire(t, p, nil)
+	if first == second {
+		t.Fatalf("оба запроса на бэкенде %s при свободном втором", first.address)
+	}
+
+	third := mustAcquire(t, p, nil)
+	light := first
+	if third == first {
+		light = second
+	}
+	if b := mustAcquire(t, p, nil); b != light {
+		t.Fatalf("выбран бэкенд %s, ожидался менее занятый %s", b.address, light.address)
+	}
+}
+
+func TestPoolAvoid(t *testing.T) {
+	p, _ := newTestPool(1, 1)
+	a, b := p.backends[0], p.backends[1]
+
+	if got := mustAcquire(t, p, a); got != b {
+		t.Fatalf("выбран бэкенд %s, которого следовало избегать", got.address)
+	}
+	// Других свободных бэкендов нет, поэтому выбирается и avoid.
+	if got := mustAcquire(t, p, a); got != a {
+		t.Fatalf("выбран бэкенд %s, ожидался %s", got.address, a.address)
+	}
+}
+
+func TestPoolLimit(t *testing.T) {
+	p, _ := newTestPool(1)
+	b := mustAcquire(t, p, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := p.acquire(ctx, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ошибка %v, ожидалось ожидание свободного места", err)
+	}
+
+	go p.release(b)
+	if got := mustAcquire(t, p, nil); got != b {
+		t.Fatalf("выбран бэкенд %s", got.address)
+	}
+}
+
+func TestPoolNoHealthyBackends(t *testing.T) {
+	p, health := newTestPool(1, 1)
+	for i, b := range p.backends {
+		health[i].set(healthpb.HealthCheckResponse_NOT_SERVING, nil)
+		p.probe(b)
+	}
+
+	// Ждать нечего: пользователь сразу узнаёт, что сервис занят.
+	if _, err := p.acquire(context.Background(), nil); !errors.As(err, &defs.ErrServiceBusy{}) {
+		t.Fatalf("ошибка %v, ожидалась %T", err, defs.ErrServiceBusy{})
+	}
+}
+
+func TestPoolDrain(t *testing.T) {
+	p, _ := newTestPool(2, 3)
+	a := p.backends[0]
+	a.inFlight = 1
+
+	drained := make(chan error, 1)
+	go func() { drained <- p.drain(context.Background(), a.address) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !p.status()[0].Draining {
+		if time.Now().After(deadline) {
+			t.Fatal("бэкенд не выводится из балансировки")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for range 3 {
+		if b := mustAcquire(t, p, nil); b == a {
+			t.Fatal("запрос отправлен на выводимый бэкенд")
+		}
+	}
+	select {
+	case err := <-drained:
+		t.Fatalf("drain завершился до окончания запроса: %v", err)
+	default:
+	}
+
+	p.release(a)
+	if err := <-drained; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.resume(a.address); err != nil {
+		t.Fatal(err)
+	}
+	if b := mustAcquire(t, p, nil); b != a {
+		t.Fatalf("после возврата выбран бэкенд %s, ожидался %s", b.address, a.address)
+	}
+}
+
+func TestPoolHealth(t *testing.T) {
+	p, health := newTestPool(1)
+	b := p.backends[0]
+	healthy := func() bool { return p.status()[0].Healthy }
+
+	// До первой проверки бэкенд исправен.
+	if !healthy() {
+		t.Fatal("новый бэкенд считается неисправным")
+	}
+
+	steps := []struct {
+		name    string
+		status  healthpb.HealthCheckResponse_ServingStatus
+		err     error
+		busy    bool
+		healthy bool
+	}{
+		{"NOT_SERVING", healthpb.HealthCheckResponse_NOT_SERVING, nil, false, false},
+		{"SERVING", healthpb.HealthCheckResponse_SERVING, nil, false, true},
+		{"недоступен", 0, status.Error(codes.Unavailable, "нет соединения"), false, false},
+		{"без health-сервиса", 0, status.Error(codes.Unimplemented, "нет сервиса"), false, true},
+		{"таймаут под нагрузкой", 0, status.Error(codes.DeadlineExceeded, "таймаут"), true, true},
+		{"таймаут без запросов", 0, status.Error(codes.DeadlineExceeded, "таймаут"), false, false},
+	}
+	for _, step := range steps {
+		health[0].set(step.status, step.err)
+		if step.busy {
+			b.inFlight = 1
+		}
+		p.probe(b)
+		b.inFlight = 0
+		if healthy() != step.healthy {
+			t.Fatalf("%s: исправен %t, ожидалось %t", step.name, healthy(), step.healthy)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Security — настройки защиты канала до AudioProcessor. Без TLS канал
+// не шифруется, и любой в сети может отправлять запросы и получать
+// образцы голосов пользователей.
+type Security struct {
+	// TLS включает шифрование канала.
+	TLS bool
+	// CAFile — сертификат CA, которым подписан сертификат сервера.
+	// Пустой — системные корневые сертификаты.
+	CAFile string
+	// CertFile и KeyFile — сертификат клиента для взаимной
+	// аутентификации (mTLS).
+	CertFile string
+	KeyFile  string
+	// ServerName переопределяет имя сервера при проверке сертификата,
+	// если оно не совпадает с адресом.
+	ServerName string
+	// Token отправляется в каждом запросе в заголовке authorization.
+	Token string
+}
+
+func (s Security) dialOptions() ([]grpc.DialOption, error) {
+	if !s.TLS {
+		if s.Token != "" {
+			return nil, errors.New("токен можно передавать только по TLS")
+		}
+		if s.CertFile != "" || s.CAFile != "" {
+			return nil, errors.New("сертификаты указаны, но TLS выключен")
+		}
+		return []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, nil
+	}
+
+	config, err := s.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(config))}
+	if s.Token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials{token: s.Token}))
+	}
+	return opts, nil
+}
+
+func (s Security) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: s.ServerName,
+	}
+
+	if s.CAFile != "" {
+		pem, err := os.ReadFile(s.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения сертификата CA: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("в %s нет сертификатов", s.CAFile)
+		}
+		config.RootCAs = roots
+	}
+
+	if (s.CertFile == "") != (s.KeyFile == "") {
+		return nil, errors.New("для mTLS нужны и сертификат, и ключ клиента")
+	}
+	if s.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.CertFile, s.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения сертификата клиента: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// tokenCredentials добавляет к каждому запросу заголовок
+// "authorization: Bearer <token>".
+type tokenCredentials struct {
+	token string
+}
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+// RequireTransportSecurity запрещает отправку токена по
+// незашифрованному каналу.
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return true
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "kursach/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// dial подключается к серверу напрямую, без пула: ошибка
+// аутентификации приходит в ответе, а не теряется в проверках
+// здоровья бэкенда.
+func dial(t *testing.T, addr string, security Security) pb.AudioProcessorClient {
+	t.Helper()
+
+	opts, err := security.dialOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := grpc.NewClient(addr, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewAudioProcessorClient(conn)
+}
+
+func transcribe(t *testing.T, c pb.AudioProcessorClient) error {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := c.Transcribe(ctx, &pb.TranscribeRequest{Audio: &pb.AudioFile{Data: []byte("audio")}})
+	return err
+}
+
+func TestToken(t *testing.T) {
+	pki := newTestPKI(t)
+	server := startServer(t, serverConfig{PKI: pki, Token: "secret"})
+
+	tests := []struct {
+		name  string
+		token string
+		code  codes.Code
+	}{
+		{"верный", "secret", codes.OK},
+		{"неверный", "wrong", codes.Unauthenticated},
+		{"без токена", "", codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := dial(t, server.Addr, Security{TLS: true, CAFile: pki.CAFile, Token: tt.token})
+			if code := status.Code(transcribe(t, c)); code != tt.code {
+				t.Fatalf("код %s, ожидался %s", code, tt.code)
+			}
+		})
+	}
+
+	t.Run("через пул", func(t *testing.T) {
+		c := newTestClient(t, server.Addr, Security{TLS: true, CAFile: pki.CAFile, Token: "secret"})
+		if _, err := c.Transcribe(context.Background(), []byte("audio"), ""); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestMutualTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	server := startServer(t, serverConfig{PKI: pki, MutualTLS: true})
+
+	c := dial(t, server.Addr, Security{TLS: true, CAFile: pki.CAFile, CertFile: pki.ClientCert, KeyFile: pki.ClientKey})
+	if err := transcribe(t, c); err != nil {
+		t.Fatalf("запрос с сертификатом клиента: %v", err)
+	}
+
+	// Без сертификата сервер разрывает соединение при рукопожатии.
+	c = dial(t, server.Addr, Security{TLS: true, CAFile: pki.CAFile})
+	if code := status.Code(transcribe(t, c)); code != codes.Unavailable {
+		t.Fatalf("запрос без сертификата клиента: код %s, ожидался %s", code, codes.Unavailable)
+	}
+}
+
+func TestSecurityDialOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		security Security
+		ok       bool
+	}{
+		{"без TLS", Security{}, true},
+		{"токен без TLS", Security{Token: "secret"}, false},
+		{"CA без TLS", Security{CAFile: "ca.pem"}, false},
+		{"сертификат без ключа", Security{TLS: true, CertFile: "client.pem"}, false},
+		{"нет файла CA", Security{TLS: true, CAFile: "missing.pem"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.security.dialOptions()
+			if (err == nil) != tt.ok {
+				t.Fatalf("ошибка %v, ожидался успех: %t", err, tt.ok)
+			}
+		})
+	}
+}
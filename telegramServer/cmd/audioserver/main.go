@@ -0,0 +1,186 @@
+// Команда audioserver запускает эталонный AudioProcessor без GPU: его
+// можно указать в GRPC_SERVERS вместо pythonServer при локальной
+// разработке и в CI.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"kursach/audioserver"
+	"kursach/tracing"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func main() {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		log.Fatalf("failed to create logger: %v", err)
+	}
+
+	opts := audioserver.Options{
+		Latency:     getEnvDuration("LATENCY", 0),
+		RuneLatency: getEnvDuration("RUNE_LATENCY", 0),
+		FailureRate: getEnvFloat("FAILURE_RATE", 0),
+		FailureCode: getEnvCode("FAILURE_CODE", codes.Unavailable),
+		ErrorRate:   getEnvFloat("ERROR_RATE", 0),
+		Transcript:  getEnv("TRANSCRIPT", "Тестовая расшифровка"),
+		Seed:        getEnvUint64("SEED", 1),
+	}
+	server := audioserver.New(opts, logger)
+
+	stopTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    getEnv("TRACING_EXPORTER", tracing.ExporterNone),
+		ServiceName: "audioserver",
+		SampleRatio: getEnvFloat("TRACING_SAMPLE_RATIO", 1),
+	})
+	if err != nil {
+		logger.Fatal("Ошибка настройки трейсинга", zap.Error(err))
+	}
+	defer func() { _ = stopTracing(context.Background()) }()
+
+	grpcOpts, err := serverOptions(server)
+	if err != nil {
+		logger.Fatal("Ошибка настройки сервера", zap.Error(err))
+	}
+	srv := grpc.NewServer(grpcOpts...)
+	server.Register(srv)
+
+	addr := getEnv("ADDR", ":50051")
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.Fatal("Ошибка запуска сервера", zap.String("addr", addr), zap.Error(err))
+	}
+
+	// Остановка как у AudioProcessor: сначала NOT_SERVING, чтобы бот
+	// перестал присылать запросы, затем ожидание начатых.
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+		<-stop
+		logger.Info("Остановка: новые запросы не принимаются, начатые дорабатываются")
+		server.Shutdown()
+		time.Sleep(getEnvDuration("DRAIN_DELAY", 0))
+		srv.GracefulStop()
+	}()
+
+	logger.Info("Эталонный AudioProcessor запущен", zap.String("addr", addr), zap.Stringer("options", opts))
+	if err := srv.Serve(lis); err != nil {
+		logger.Fatal("Ошибка работы сервера", zap.Error(err))
+	}
+}
+
+// serverOptions настраивает TLS и проверку токена теми же переменными
+// окружения, что и pythonServer: TLS_CERT, TLS_KEY, TLS_CLIENT_CA и
+// AUTH_TOKEN.
+func serverOptions(server *audioserver.Server) ([]grpc.ServerOption, error) {
+	interceptors := []grpc.UnaryServerInterceptor{server.LogRequests}
+	// Спаны запросов продолжают трейс бота из заголовка traceparent.
+	opts := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler(otelgrpc.WithFilter(filters.Not(filters.HealthCheck())))),
+	}
+
+	certFile, keyFile, clientCA := getEnv("TLS_CERT", ""), getEnv("TLS_KEY", ""), getEnv("TLS_CLIENT_CA", "")
+	token := getEnv("AUTH_TOKEN", "")
+	if certFile == "" {
+		if token != "" || clientCA != "" {
+			return nil, errors.New("AUTH_TOKEN и TLS_CLIENT_CA требуют TLS_CERT и TLS_KEY")
+		}
+		return append(opts, grpc.ChainUnaryInterceptor(interceptors...)), nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения сертификата сервера: %w", err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if clientCA != "" {
+		pem, err := os.ReadFile(clientCA)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения сертификата CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("в %s нет сертификатов", clientCA)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+
+	if token != "" {
+		interceptors = append([]grpc.UnaryServerInterceptor{audioserver.CheckToken(token)}, interceptors...)
+	}
+	return append(opts, grpc.ChainUnaryInterceptor(interceptors...)), nil
+}
+
+func getEnv(key, fallback string) string {
+	if val, ok := os.LookupEnv(key); ok && val != "" {
+		return val
+	}
+	return fallback
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	val := getEnv(key, "")
+	if val == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		log.Fatalf("Переменная окружения %s должна быть длительностью, например, 500ms: %v", key, err)
+	}
+	return d
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	val := getEnv(key, "")
+	if val == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		log.Fatalf("Переменная окружения %s должна быть числом: %v", key, err)
+	}
+	return f
+}
+
+func getEnvUint64(key string, fallback uint64) uint64 {
+	val := getEnv(key, "")
+	if val == "" {
+		return fallback
+	}
+	n, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		log.Fatalf("Переменная окружения %s должна быть числом: %v", key, err)
+	}
+	return n
+}
+
+// getEnvCode разбирает код gRPC по имени, например, UNAVAILABLE.
+func getEnvCode(key string, fallback codes.Code) codes.Code {
+	val := getEnv(key, "")
+	if val == "" {
+		return fallback
+	}
+	var code codes.Code
+	if err := code.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(val)))); err != nil {
+		log.Fatalf("Переменная окружения %s должна быть кодом gRPC: %v", key, err)
+	}
+	return code
+}
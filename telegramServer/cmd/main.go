@@ -11,5 +11,8 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to init app: %v", err)
 	}
-	app.Start()
+	err = app.Run()
+	if err != nil {
+		log.Fatalf("app stopped with error: %v", err)
+	}
 }
@@ -3,30 +3,134 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
+)
+
+// Способы получения обновлений.
+const (
+	UpdatesPolling = "polling"
+	UpdatesWebhook = "webhook"
 )
 
 type Config struct {
 	TelegramToken string
-	GRPCHost      string
-	GRPCPort      string
 	DBHost        string
 	DBPort        string
 	DBUser        string
 	DBPassword    string
 	DBName        string
+
+	// GRPCServers — адреса AudioProcessor через запятую, у адреса можно
+	// указать лимит запросов: "tts-1:50051,tts-2:50051=2".
+	GRPCServers string
+	// GRPCConcurrency — лимит одновременных запросов на сервер по
+	// умолчанию.
+	GRPCConcurrency int
+	// Защита канала до AudioProcessor: TLS, сертификат клиента для mTLS
+	// и токен, который передаётся в каждом запросе.
+	GRPCTLS        bool
+	GRPCCAFile     string
+	GRPCCertFile   string
+	GRPCKeyFile    string
+	GRPCServerName string
+	GRPCToken      string
+
+	CallbackSecret string
+
+	// AdminListen — адрес служебного HTTP-сервера: /healthz, /readyz,
+	// /metrics. Эти пути доступны без токена, поэтому по умолчанию
+	// сервер слушает только localhost. AdminToken открывает pprof и
+	// управление серверами AudioProcessor, пустой отключает их.
+	AdminListen string
+	AdminToken  string
+
+	// ShutdownTimeout — сколько бот при остановке дорабатывает начатые
+	// обновления и задачи. Должен быть меньше stop_grace_period.
+	ShutdownTimeout time.Duration
+
+	// UpdatesMode — способ получения обновлений: "polling" (по
+	// умолчанию) или "webhook".
+	UpdatesMode string
+	// WebhookURL — публичный адрес https://, на который Telegram
+	// отправляет обновления. WebhookListen — адрес HTTP-сервера бота.
+	WebhookURL    string
+	WebhookListen string
+	// WebhookSecret проверяется в заголовке каждого запроса. Пустой —
+	// выводится из токена бота.
+	WebhookSecret string
+	// WebhookTLSCert и WebhookTLSKey включают TLS на сервере бота, если
+	// перед ним нет балансировщика. WebhookCert — самоподписанный
+	// сертификат, который загружается в Telegram.
+	WebhookTLSCert        string
+	WebhookTLSKey         string
+	WebhookCert           string
+	WebhookMaxConnections int
+	// WebhookDeleteOnStop удаляет вебхук при остановке. С несколькими
+	// копиями бота его нужно оставить выключенным.
+	WebhookDeleteOnStop bool
+
+	// TracingExporter — куда отправлять трейсы: "none" (по умолчанию),
+	// "stdout" или "otlp". TracingSampleRatio — доля записываемых
+	// трейсов от 0 до 1.
+	TracingExporter    string
+	TracingSampleRatio float64
+
+	// InlineCacheChatID — чат, куда бот загружает голосовые для inline
+	// режима, чтобы получить file_id. 0 отключает inline режим.
+	InlineCacheChatID int64
 }
 
 func LoadConfig() Config {
+	token := mustGetEnv("TELEGRAM_TOKEN")
 	return Config{
-		TelegramToken: mustGetEnv("TELEGRAM_TOKEN"),
-		GRPCHost:      mustGetEnv("GRPC_SERVER_HOST"),
-		GRPCPort:      mustGetEnv("GRPC_SERVER_PORT"),
+		TelegramToken: token,
 		DBHost:        mustGetEnv("DB_HOST"),
 		DBPort:        mustGetEnv("DB_PORT"),
 		DBUser:        mustGetEnv("DB_USER"),
 		DBPassword:    mustGetEnv("DB_PASSWORD"),
 		DBName:        mustGetEnv("DB_NAME"),
+
+		GRPCServers:     grpcServers(),
+		GRPCConcurrency: int(getEnvInt64("GRPC_CONCURRENCY", 1)),
+		GRPCTLS:         getEnvBool("GRPC_TLS", false),
+		GRPCCAFile:      getEnv("GRPC_TLS_CA", ""),
+		GRPCCertFile:    getEnv("GRPC_TLS_CERT", ""),
+		GRPCKeyFile:     getEnv("GRPC_TLS_KEY", ""),
+		GRPCServerName:  getEnv("GRPC_TLS_SERVER_NAME", ""),
+		GRPCToken:       getEnv("GRPC_TOKEN", ""),
+
+		CallbackSecret: getEnv("CALLBACK_SECRET", token),
+
+		AdminListen: getEnv("ADMIN_LISTEN", "127.0.0.1:8080"),
+		AdminToken:  getEnv("ADMIN_TOKEN", ""),
+
+		ShutdownTimeout: getEnvDuration("SHUTDOWN_TIMEOUT", 90*time.Second),
+
+		UpdatesMode:           getEnv("UPDATES_MODE", UpdatesPolling),
+		WebhookURL:            getEnv("WEBHOOK_URL", ""),
+		WebhookListen:         getEnv("WEBHOOK_LISTEN", ":8443"),
+		WebhookSecret:         getEnv("WEBHOOK_SECRET", ""),
+		WebhookTLSCert:        getEnv("WEBHOOK_TLS_CERT", ""),
+		WebhookTLSKey:         getEnv("WEBHOOK_TLS_KEY", ""),
+		WebhookCert:           getEnv("WEBHOOK_CERT", ""),
+		WebhookMaxConnections: int(getEnvInt64("WEBHOOK_MAX_CONNECTIONS", 0)),
+		WebhookDeleteOnStop:   getEnvBool("WEBHOOK_DELETE_ON_STOP", false),
+
+		TracingExporter:    getEnv("TRACING_EXPORTER", "none"),
+		TracingSampleRatio: getEnvFloat("TRACING_SAMPLE_RATIO", 1),
+
+		InlineCacheChatID: getEnvInt64("INLINE_CACHE_CHAT_ID", 0),
+	}
+}
+
+// grpcServers читает список серверов из GRPC_SERVERS, а если он не
+// задан — единственный сервер из GRPC_SERVER_HOST и GRPC_SERVER_PORT.
+func grpcServers() string {
+	if servers := os.Getenv("GRPC_SERVERS"); servers != "" {
+		return servers
 	}
+	return mustGetEnv("GRPC_SERVER_HOST") + ":" + mustGetEnv("GRPC_SERVER_PORT")
 }
 
 func mustGetEnv(key string) string {
@@ -36,3 +140,59 @@ func mustGetEnv(key string) string {
 	}
 	return val
 }
+
+func getEnv(key string, fallback string) string {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	return val
+}
+
+func getEnvInt64(key string, fallback int64) int64 {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		log.Fatalf("Переменная окружения %s должна быть числом: %v", key, err)
+	}
+	return n
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		log.Fatalf("Переменная окружения %s должна быть числом: %v", key, err)
+	}
+	return f
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		log.Fatalf("Переменная окружения %s должна быть true или false: %v", key, err)
+	}
+	return b
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		log.Fatalf("Переменная окружения %s должна быть длительностью, например, 90s: %v", key, err)
+	}
+	return d
+}
@@ -1,13 +1,172 @@
 package defs
 
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
 const (
 	WaitingModelName       = "waiting_model_name"
-	WaitingDeleteModelName = "waiting_delete_model_name"
+	WaitingRenameModelName = "waiting_rename_model_name"
 	WaitingVoice           = "waiting_voice"
+	WaitingSample          = "waiting_sample"
 	FreeState              = "free_state"
 	MaxModels              = 5
+	ModelsPerPage          = 4
+
+	// CallbackTTL — сколько живут inline-кнопки, после этого бот
+	// просит открыть меню заново.
+	CallbackTTL = 7 * 24 * time.Hour
+
+	// InlineDebounce — пауза после inline-запроса: если пользователь
+	// продолжает печатать, предыдущий запрос не синтезируется.
+	InlineDebounce = 800 * time.Millisecond
+
+	// Кэш синтеза хранит готовые голосовые в памяти и ограничен как
+	// числом записей, так и их суммарным размером.
+	SpeechCacheSize  = 1000
+	SpeechCacheBytes = 64 << 20
+
+	// MaxDownloadSize — ограничение Bot API на скачивание файлов ботом.
+	MaxDownloadSize = 20 << 20
+	// MessageChunkLen — длина части длинного текста, который отправляется
+	// несколькими сообщениями. Telegram принимает до 4096 символов.
+	MessageChunkLen = 4000
+	// BookSegmentLen — длина фрагмента главы, который синтезируется за
+	// один запрос к AudioProcessor.
+	BookSegmentLen       = 400
+	BookSegmentPause     = 300 * time.Millisecond
+	BookProgressInterval = 10 * time.Second
+	// QueueWorkers — число одновременно выполняемых задач очереди.
+	// Синтез идёт на одном сервере, поэтому задачи выполняются по одной.
+	QueueWorkers = 1
+	// BusyRetryDelay — через сколько повторяется озвучка главы, если
+	// AudioProcessor был недоступен.
+	BusyRetryDelay = time.Minute
+	// AudiobookLease — на сколько книга закрепляется за копией бота.
+	// Аренда продлевается с каждой главой и во время её озвучки, книги с
+	// истёкшей арендой подхватывают другие копии.
+	AudiobookLease = 10 * time.Minute
+
+	// MaxVideoNoteDuration — Telegram не показывает видеосообщения
+	// длиннее минуты, более длинная озвучка отправляется голосовым.
+	MaxVideoNoteDuration = 60
+
+	// Темп речи: NormalSpeed — обычный, кнопки "Медленнее" и "Быстрее"
+	// меняют его на SpeedStep в пределах MinSpeed–MaxSpeed.
+	NormalSpeed = 1.0
+	SpeedStep   = 0.25
+	MinSpeed    = 0.5
+	MaxSpeed    = 2.0
+
+	// GenerationsPerPage — записей на странице /history.
+	GenerationsPerPage = 5
+	// DefaultHistoryDays — сколько дней хранится история озвучки, если
+	// пользователь не выбрал другой срок.
+	DefaultHistoryDays = 30
+	// HiddenGenerationDays — сколько дней хранятся записи озвучки
+	// пользователей с отключённой историей: на них ссылаются кнопки под
+	// озвучкой, но в /history они не показываются.
+	HiddenGenerationDays = 1
+
+	AudiobookProcessing = "processing"
+	AudiobookDone       = "done"
+	AudiobookFailed     = "failed"
+)
+
+// Форматы, в которых бот присылает озвученный текст.
+const (
+	OutputVoice     = "voice"
+	OutputMP3       = "mp3"
+	OutputM4A       = "m4a"
+	OutputWAV       = "wav"
+	OutputVideoNote = "video_note"
 )
 
+var OutputFormats = []string{OutputVoice, OutputMP3, OutputM4A, OutputWAV, OutputVideoNote}
+
+// HistoryDays — сроки хранения истории, из которых выбирает
+// пользователь. 0 — история не сохраняется.
+var HistoryDays = []int{0, 7, 30, 90, 365}
+
+type Model struct {
+	ID     int64
+	UserID int64
+	Name   string
+}
+
+// Scope — ключ состояния диалога: пользователь в конкретном чате.
+// В личных сообщениях ChatID совпадает с UserID.
+type Scope struct {
+	ChatID int64
+	UserID int64
+}
+
+// Speech — озвученный текст в формате голосового сообщения.
+type Speech struct {
+	// Key — адрес записи в кэше синтеза.
+	Key      string
+	ModelID  int64
+	Speed    float64
+	Opus     []byte
+	Duration int
+}
+
+type ChatSettings struct {
+	ChatID int64
+	// AdminOnly разрешает синтез в группе только администраторам.
+	AdminOnly bool
+	// Model — общая модель чата. Если ID равен 0, каждый участник
+	// использует свою активную модель.
+	Model Model
+}
+
+type Audiobook struct {
+	ID     int64
+	UserID int64
+	ChatID int64
+	Model  Model
+	Title  string
+	// Lang — язык интерфейса пользователя на момент загрузки книги,
+	// на нём пишутся сообщения о прогрессе.
+	Lang   string
+	Status string
+	// MessageID — сообщение с прогрессом озвучки, 0 если его нет.
+	MessageID    int
+	Chapters     int
+	ChaptersDone int
+}
+
+type Chapter struct {
+	BookID int64
+	Index  int
+	Title  string
+	Text   string
+}
+
+// Generation — запись истории озвучки. Если модель удалена, Model.ID
+// равен 0, а имя модели сохраняется.
+type Generation struct {
+	ID        int64
+	UserID    int64
+	Model     Model
+	Text      string
+	Params    GenerationParams
+	Duration  int
+	FileID    string
+	CreatedAt time.Time
+	// Hidden — запись сохранена только для кнопок под озвучкой и не
+	// показывается в истории.
+	Hidden bool
+}
+
+// GenerationParams — параметры, с которыми была озвучена запись.
+type GenerationParams struct {
+	Format string  `json:"format"`
+	Speed  float64 `json:"speed"`
+}
+
 type ErrNoModel struct {
 	error string
 }
@@ -15,3 +174,45 @@ type ErrNoModel struct {
 func (e ErrNoModel) Error() string {
 	return e.error
 }
+
+type ErrNoAudiobook struct {
+	error string
+}
+
+func (e ErrNoAudiobook) Error() string {
+	return e.error
+}
+
+type ErrNoGeneration struct {
+	error string
+}
+
+func (e ErrNoGeneration) Error() string {
+	return e.error
+}
+
+// ErrInvalidModelName — имя модели нельзя использовать: оно входит в
+// путь к файлу образца.
+type ErrInvalidModelName struct{}
+
+func (e ErrInvalidModelName) Error() string {
+	return "недопустимое имя модели"
+}
+
+// ValidModelName проверяет, что имя модели можно использовать как имя
+// файла в каталоге пользователя: без разделителей пути, ".." и
+// управляющих символов.
+func ValidModelName(name string) bool {
+	if name == "" || strings.Contains(name, "..") || strings.ContainsAny(name, `/\`) {
+		return false
+	}
+	return !strings.ContainsFunc(name, unicode.IsControl)
+}
+
+// ErrServiceBusy — AudioProcessor недоступен: перезапускается или
+// перегружен. Запрос стоит повторить позже.
+type ErrServiceBusy struct{}
+
+func (e ErrServiceBusy) Error() string {
+	return "сервис озвучки временно недоступен"
+}
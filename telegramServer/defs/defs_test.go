@@ -0,0 +1,25 @@
+package defs
+
+import "testing"
+
+func TestValidModelName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"Мой голос", true},
+		{"voice.v2", true},
+		{"", false},
+		{"../other", false},
+		{"..", false},
+		{"a/b", false},
+		{`a\b`, false},
+		{"a\nb", false},
+		{"a\x00b", false},
+	}
+	for _, tt := range tests {
+		if got := ValidModelName(tt.name); got != tt.want {
+			t.Errorf("ValidModelName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"gopkg.in/telebot.v3"
+	"kursach/book"
+	"kursach/client"
+	"kursach/defs"
+	"kursach/i18n"
+	"kursach/queue"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HandleDocument принимает книгу в .txt, .md, .fb2 или .epub и ставит
+// её главы в очередь на озвучку текущей моделью пользователя.
+func (h *Handler) HandleDocument(c telebot.Context) error {
+	ctx := requestContext(c)
+	// Аудио и видео, отправленные файлом, обрабатываются как голосовые.
+	if mediaFile(c.Message()) != nil {
+		return h.HandleVoice(c)
+	}
+	if isGroup(c) {
+		return nil
+	}
+
+	doc := c.Message().Document
+	scope := dialogScope(c)
+	h.log.Info("HandleDocument called", zap.Int64("userID", scope.UserID), zap.String("fileName", doc.FileName))
+
+	if !book.Supported(doc.FileName) {
+		return c.Send(h.t(c, "book.unsupported"))
+	}
+	if doc.FileSize > defs.MaxDownloadSize {
+		return c.Send(h.t(c, "file.too_large", defs.MaxDownloadSize>>20))
+	}
+
+	model, err := h.service.ResolveModel(ctx, scope)
+	if err != nil {
+		if errors.Is(err, defs.ErrNoModel{}) {
+			return c.Send(h.t(c, "model.required"))
+		}
+		h.log.Error("Ошибка получения модели", zap.Error(err))
+		return c.Send(h.t(c, "error.generic"))
+	}
+
+	data, err := h.downloadFile(c, &doc.File)
+	if err != nil {
+		h.log.Error("Ошибка загрузки документа", zap.Error(err))
+		return c.Send(h.t(c, "error.generic"))
+	}
+
+	parsed, err := book.Parse(doc.FileName, data)
+	if err != nil {
+		h.log.Warn("Ошибка разбора книги", zap.String("fileName", doc.FileName), zap.Error(err))
+		return c.Send(h.t(c, "book.parse_failed"))
+	}
+
+	audiobook, err := h.service.CreateAudiobook(ctx, scope, i18n.Lang(c), model, parsed)
+	if err != nil {
+		h.log.Error("Ошибка сохранения книги", zap.Error(err))
+		return c.Send(h.t(c, "error.generic"))
+	}
+
+	msg, err := c.Bot().Send(c.Chat(), h.bookQueued(audiobook))
+	if err != nil {
+		h.log.Warn("Ошибка отправки сообщения о прогрессе", zap.Error(err))
+	} else if err := h.service.SetAudiobookMessage(ctx, audiobook.ID, msg.ID); err != nil {
+		h.log.Warn("Ошибка сохранения сообщения о прогрессе", zap.Error(err))
+	}
+
+	h.enqueueAudiobook(ctx, c.Bot(), audiobook.ID)
+	return nil
+}
+
+// audiobookChains — книги, у которых в этой копии бота есть цепочка
+// задач: глава ждёт в очереди или озвучивается. Каждая глава ставит в
+// очередь следующую, поэтому вторая цепочка той же книги озвучивала бы
+// главы дважды.
+type audiobookChains struct {
+	mu  sync.Mutex
+	ids map[int64]struct{}
+}
+
+func newAudiobookChains() *audiobookChains {
+	return &audiobookChains{ids: make(map[int64]struct{})}
+}
+
+// start отмечает начало цепочки книги. Возвращает false, если цепочка
+// уже есть.
+func (c *audiobookChains) start(bookID int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.ids[bookID]; ok {
+		return false
+	}
+	c.ids[bookID] = struct{}{}
+	return true
+}
+
+func (c *audiobookChains) stop(bookID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.ids, bookID)
+}
+
+func (c *audiobookChains) list() []int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids := make([]int64, 0, len(c.ids))
+	for id := range c.ids {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// ResumeAudiobooks ставит в очередь книги, озвучка которых прервалась
+// при остановке бота, и до отмены ctx подхватывает книги копий бота,
+// аренда которых истекла. Книги закрепляются за копией атомарно,
+// поэтому каждую озвучивает одна копия. Главы, озвученные до
+// остановки, не повторяются.
+func (h *Handler) ResumeAudiobooks(ctx context.Context, bot *telebot.Bot) {
+	h.claimAudiobooks(ctx, bot, true)
+
+	ticker := time.NewTicker(defs.AudiobookLease / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.renewAudiobooks(ctx)
+			h.claimAudiobooks(ctx, bot, false)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// renewAudiobooks продлевает аренду книг, главы которых ждут в
+// очереди: при нескольких книгах глава может ждать дольше аренды, и
+// книгу не должна забрать другая копия.
+func (h *Handler) renewAudiobooks(ctx context.Context) {
+	for _, id := range h.audiobooks.list() {
+		claimed, err := h.service.ClaimAudiobook(ctx, id)
+		if err != nil {
+			h.log.Warn("Ошибка продления аренды книги", zap.Int64("bookID", id), zap.Error(err))
+			continue
+		}
+		// Цепочка завершится сама, когда её глава не получит аренду.
+		if !claimed {
+			h.log.Info("Книгу озвучивает другая копия бота", zap.Int64("bookID", id))
+		}
+	}
+}
+
+func (h *Handler) claimAudiobooks(ctx context.Context, bot *telebot.Bot, reclaimOwn bool) {
+	ids, err := h.service.ClaimAudiobooks(ctx, reclaimOwn)
+	if err != nil {
+		h.log.Error("Ошибка получения незавершённых книг", zap.Error(err))
+		return
+	}
+
+	resumed := 0
+	for _, id := range ids {
+		if h.enqueueAudiobook(ctx, bot, id) {
+			resumed++
+		}
+	}
+	if resumed > 0 {
+		h.log.Info("Озвучка книг возобновлена", zap.Int("books", resumed))
+	}
+}
+
+// ReleaseAudiobooks снимает аренду с книг при остановке, чтобы их
+// сразу подхватили другие копии бота.
+func (h *Handler) ReleaseAudiobooks(ctx context.Context) {
+	if err := h.service.ReleaseAudiobooks(ctx); err != nil {
+		h.log.Warn("Ошибка снятия аренды книг", zap.Error(err))
+	}
+}
+
+// enqueueAudiobook начинает цепочку озвучки книги, если её ещё нет.
+// Каждая глава — отдельная задача, которая ставит в очередь следующую,
+// поэтому книги разных пользователей озвучиваются по очереди, а не
+// целиком одна за другой.
+func (h *Handler) enqueueAudiobook(ctx context.Context, bot *telebot.Bot, bookID int64) bool {
+	if !h.audiobooks.start(bookID) {
+		return false
+	}
+	h.queue.Submit(ctx, h.audiobookJob(bot, bookID))
+	return true
+}
+
+func (h *Handler) audiobookJob(bot *telebot.Bot, bookID int64) queue.Job {
+	return queue.Job{
+		Name: "audiobook:" + strconv.FormatInt(bookID, 10),
+		Run: func(ctx context.Context) error {
+			return h.processAudiobook(ctx, bot, bookID)
+		},
+		Dropped: func(ctx context.Context) {
+			h.audiobooks.stop(bookID)
+			h.notifyAudiobookPaused(ctx, bot, bookID)
+		},
+	}
+}
+
+// notifyAudiobookPaused предупреждает пользователя, что озвучка книги
+// прервалась из-за остановки бота. Книга продолжится после перезапуска
+// или на другой копии бота.
+func (h *Handler) notifyAudiobookPaused(ctx context.Context, bot *telebot.Bot, bookID int64) {
+	audiobook, err := h.service.GetAudiobook(ctx, bookID)
+	if err != nil {
+		if !errors.Is(err, defs.ErrNoAudiobook{}) {
+			h.log.Warn("Ошибка получения книги", zap.Int64("bookID", bookID), zap.Error(err))
+		}
+		return
+	}
+	if audiobook.Status != defs.AudiobookProcessing {
+		return
+	}
+
+	if _, err := bot.Send(&telebot.Chat{ID: audiobook.ChatID}, i18n.T(audiobook.Lang, "book.paused", audiobook.Title)); err != nil {
+		h.log.Warn("Ошибка отправки сообщения о паузе озвучки", zap.Int64("bookID", bookID), zap.Error(err))
+	}
+}
+
+func (h *Handler) processAudiobook(ctx context.Context, bot *telebot.Bot, bookID int64) error {
+	// Цепочка книги заканчивается, если глава не поставила следующую
+	// задачу.
+	next := false
+	defer func() {
+		if !next {
+			h.audiobooks.stop(bookID)
+		}
+	}()
+
+	// Пока задача ждала в очереди, аренда могла истечь, и книгу
+	// подхватила другая копия бота.
+	claimed, err := h.service.ClaimAudiobook(ctx, bookID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		h.log.Info("Книгу озвучивает другая копия бота", zap.Int64("bookID", bookID))
+		return nil
+	}
+
+	audiobook, err := h.service.GetAudiobook(ctx, bookID)
+	if err != nil {
+		// Книга удалена вместе с моделью.
+		if errors.Is(err, defs.ErrNoAudiobook{}) {
+			return nil
+		}
+		return err
+	}
+	if audiobook.Status != defs.AudiobookProcessing {
+		return nil
+	}
+
+	chapter, ok, err := h.service.GetNextChapter(ctx, bookID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		h.editBookProgress(bot, audiobook, i18n.N(audiobook.Lang, "book.done", audiobook.Chapters, audiobook.Title, audiobook.Chapters))
+		return h.service.SetAudiobookStatus(ctx, bookID, defs.AudiobookDone)
+	}
+	if chapter.Title == "" {
+		chapter.Title = i18n.T(audiobook.Lang, "book.chapter", chapter.Index+1)
+	}
+
+	var lastEdit time.Time
+	renewed := time.Now()
+	progress := func(done, total int) {
+		if time.Since(renewed) > defs.AudiobookLease/3 {
+			renewed = time.Now()
+			if _, err := h.service.ClaimAudiobook(ctx, bookID); err != nil {
+				h.log.Warn("Ошибка продления аренды книги", zap.Int64("bookID", bookID), zap.Error(err))
+			}
+		}
+		if time.Since(lastEdit) < defs.BookProgressInterval {
+			return
+		}
+		lastEdit = time.Now()
+		h.editBookProgress(bot, audiobook, i18n.T(audiobook.Lang, "book.progress",
+			audiobook.Title, chapter.Index+1, audiobook.Chapters, chapter.Title, done*100/total))
+	}
+	progress(0, 1)
+
+	requestID := fmt.Sprintf("book-%d-%d", bookID, chapter.Index+1)
+	mp3, dur, err := h.service.SynthesizeChapter(client.WithRequestID(ctx, requestID), audiobook, chapter, progress)
+	if err == nil {
+		_, err = bot.Send(&telebot.Chat{ID: audiobook.ChatID}, &telebot.Audio{
+			File:      telebot.File{FileReader: bytes.NewReader(mp3)},
+			Duration:  dur,
+			Title:     chapter.Title,
+			Performer: audiobook.Title,
+			MIME:      "audio/mpeg",
+			FileName:  chapterFileName(chapter),
+		})
+	}
+	if err != nil {
+		// При остановке бота глава будет озвучена заново после запуска.
+		if ctx.Err() != nil {
+			return err
+		}
+		// Пока AudioProcessor недоступен, глава откладывается, а не
+		// считается ошибкой.
+		if errors.Is(err, defs.ErrServiceBusy{}) {
+			next = true
+			h.queue.SubmitAfter(ctx, defs.BusyRetryDelay, h.audiobookJob(bot, bookID))
+			return fmt.Errorf("озвучка главы %d книги %d отложена: %w", chapter.Index, bookID, err)
+		}
+		h.editBookProgress(bot, audiobook, i18n.T(audiobook.Lang, "book.failed", audiobook.Title, chapter.Index+1))
+		if statusErr := h.service.SetAudiobookStatus(ctx, bookID, defs.AudiobookFailed); statusErr != nil {
+			h.log.Error("Ошибка сохранения статуса книги", zap.Error(statusErr))
+		}
+		return fmt.Errorf("ошибка озвучки главы %d книги %d: %w", chapter.Index, bookID, err)
+	}
+
+	if err := h.service.MarkChapterDone(ctx, bookID, chapter.Index); err != nil {
+		return err
+	}
+
+	next = true
+	h.queue.Submit(ctx, h.audiobookJob(bot, bookID))
+	return nil
+}
+
+func chapterFileName(chapter defs.Chapter) string {
+	return fmt.Sprintf("%03d %s.mp3", chapter.Index+1, safeFileName(chapter.Title))
+}
+
+func (h *Handler) bookQueued(audiobook defs.Audiobook) string {
+	return i18n.N(audiobook.Lang, "book.queued", audiobook.Chapters, audiobook.Title, audiobook.Chapters, audiobook.Model.Name)
+}
+
+func (h *Handler) editBookProgress(bot *telebot.Bot, audiobook defs.Audiobook, text string) {
+	if audiobook.MessageID == 0 {
+		return
+	}
+
+	msg := &telebot.StoredMessage{MessageID: strconv.Itoa(audiobook.MessageID), ChatID: audiobook.ChatID}
+	_, err := bot.Edit(msg, text)
+	if err != nil && !errors.Is(err, telebot.ErrSameMessageContent) {
+		h.log.Warn("Ошибка обновления прогресса книги", zap.Int64("bookID", audiobook.ID), zap.Error(err))
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"kursach/queue"
+
+	"go.uber.org/zap"
+)
+
+// bookService отдаёт одну и ту же книгу при каждом захвате, как
+// хранилище, в котором аренда книги истекла, пока её глава ждала в
+// очереди.
+type bookService struct {
+	Service
+	claimed bool
+	renewed int
+}
+
+func (s *bookService) ClaimAudiobooks(ctx context.Context, reclaimOwn bool) ([]int64, error) {
+	return []int64{1}, nil
+}
+
+func (s *bookService) ClaimAudiobook(ctx context.Context, bookID int64) (bool, error) {
+	s.renewed++
+	return s.claimed, nil
+}
+
+func TestAudiobookSingleChain(t *testing.T) {
+	service := &bookService{claimed: true}
+	// Очередь не запущена: глава книги остаётся в ней.
+	q := queue.New(1, zap.NewNop())
+	h := NewHandler(service, nil, q, 0, zap.NewNop())
+	ctx := context.Background()
+
+	h.claimAudiobooks(ctx, nil, true)
+	if q.Len() != 1 {
+		t.Fatalf("в очереди %d задач, ожидалась 1", q.Len())
+	}
+
+	// Тик ResumeAudiobooks, пока глава ждёт в очереди.
+	h.renewAudiobooks(ctx)
+	h.claimAudiobooks(ctx, nil, false)
+	if q.Len() != 1 {
+		t.Fatalf("после тика в очереди %d задач, ожидалась 1", q.Len())
+	}
+	if service.renewed != 1 {
+		t.Fatalf("аренда продлена %d раз, ожидался 1", service.renewed)
+	}
+
+	// Глава без аренды завершает цепочку, и книгу можно снова взять.
+	service.claimed = false
+	if err := h.processAudiobook(ctx, nil, 1); err != nil {
+		t.Fatal(err)
+	}
+	h.claimAudiobooks(ctx, nil, false)
+	if q.Len() != 2 {
+		t.Fatalf("в очереди %d задач, ожидалось 2", q.Len())
+	}
+}
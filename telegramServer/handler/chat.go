@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"errors"
+	"go.uber.org/zap"
+	"gopkg.in/telebot.v3"
+	"kursach/callback"
+	"kursach/defs"
+	"strings"
+	"unicode/utf16"
+)
+
+func dialogScope(c telebot.Context) defs.Scope {
+	return defs.Scope{ChatID: c.Chat().ID, UserID: c.Sender().ID}
+}
+
+func isGroup(c telebot.Context) bool {
+	chat := c.Chat()
+	return chat != nil && (chat.Type == telebot.ChatGroup || chat.Type == telebot.ChatSuperGroup)
+}
+
+// commandName возвращает команду без аргументов и без "@bot",
+// который Telegram добавляет к командам в группах.
+func commandName(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return ""
+	}
+	command, _, _ := strings.Cut(fields[0], "@")
+	return command
+}
+
+// addressedText проверяет, что сообщение в группе обращено к боту:
+// содержит упоминание бота или является ответом на его сообщение.
+// Упоминание вырезается из текста.
+func (h *Handler) addressedText(c telebot.Context) (string, bool) {
+	msg := c.Message()
+	me := c.Bot().Me
+
+	repliedToBot := msg.ReplyTo != nil && msg.ReplyTo.Sender != nil && msg.ReplyTo.Sender.ID == me.ID
+
+	mention := "@" + strings.ToLower(me.Username)
+	text := msg.Text
+	mentioned := false
+	for i := len(msg.Entities) - 1; i >= 0; i-- {
+		entity := msg.Entities[i]
+		if entity.Type != telebot.EntityMention || strings.ToLower(msg.EntityText(entity)) != mention {
+			continue
+		}
+		mentioned = true
+		text = cutEntity(text, entity)
+	}
+
+	// "@bot" ответом на сообщение озвучивает это сообщение.
+	text = strings.TrimSpace(text)
+	if mentioned && text == "" && msg.ReplyTo != nil {
+		return speechText(msg.ReplyTo), true
+	}
+	return text, mentioned || repliedToBot
+}
+
+// cutEntity удаляет entity из текста. Смещения Telegram считаются в
+// UTF-16 code units.
+func cutEntity(text string, entity telebot.MessageEntity) string {
+	units := utf16.Encode([]rune(text))
+	end := min(entity.Offset+entity.Length, len(units))
+	if entity.Offset >= end {
+		return text
+	}
+	return string(utf16.Decode(append(units[:entity.Offset:entity.Offset], units[end:]...)))
+}
+
+// Say озвучивает текст после команды: "/say текст". Если команда
+// отправлена ответом на сообщение без текста после неё, озвучивается
+// текст или подпись исходного сообщения, в том числе пересланного.
+func (h *Handler) Say(c telebot.Context) error {
+	msg := c.Message()
+	h.log.Info("Say called", zap.Int64("userID", c.Sender().ID), zap.Int64("chatID", c.Chat().ID))
+
+	text := speechText(msg)
+	if text == "" && msg.ReplyTo != nil {
+		text = speechText(msg.ReplyTo)
+		if text == "" {
+			return c.Reply(h.t(c, "say.no_text"))
+		}
+	}
+	return h.synthesize(c, text)
+}
+
+// synthesize озвучивает текст моделью, выбранной для чата, и отвечает
+// в формате, выбранном пользователем. В группах учитываются настройки
+// чата.
+func (h *Handler) synthesize(c telebot.Context, text string) error {
+	ctx := requestContext(c)
+	scope := dialogScope(c)
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return c.Send(h.t(c, "say.empty"))
+	}
+
+	if isGroup(c) {
+		settings, err := h.service.GetChatSettings(ctx, scope.ChatID)
+		if err != nil {
+			h.log.Error("Ошибка получения настроек чата", zap.Error(err))
+			return c.Reply(h.t(c, "error.generic"))
+		}
+		if settings.AdminOnly && !h.isChatAdmin(c) {
+			return c.Reply(h.t(c, "chat.admin_only"))
+		}
+	}
+
+	model, err := h.service.ResolveModel(ctx, scope)
+	if err != nil {
+		if errors.Is(err, defs.ErrNoModel{}) {
+			return c.Reply(h.t(c, "model.required"))
+		}
+		h.log.Error("Ошибка получения модели", zap.Error(err))
+		return c.Reply(h.t(c, "error.generic"))
+	}
+
+	speech, err := h.service.Synthesize(ctx, scope.UserID, model, text, defs.NormalSpeed)
+	if err != nil {
+		h.log.Error("Ошибка генерации аудио", zap.Error(err))
+		return c.Reply(h.failureText(c, err))
+	}
+
+	h.log.Info("Отправка озвучки пользователю", zap.Int64("userID", scope.UserID), zap.Int64("chatID", scope.ChatID))
+	if err := h.sendSpeech(c, model, text, speech); err != nil {
+		h.log.Error("Ошибка отправки озвучки", zap.Error(err))
+		return c.Reply(h.t(c, "error.generic"))
+	}
+	return nil
+}
+
+func (h *Handler) isChatAdmin(c telebot.Context) bool {
+	member, err := c.Bot().ChatMemberOf(c.Chat(), c.Sender())
+	if err != nil {
+		h.log.Warn("Ошибка получения участника чата", zap.Int64("chatID", c.Chat().ID), zap.Error(err))
+		return false
+	}
+	return member.Role == telebot.Administrator || member.Role == telebot.Creator
+}
+
+// ChatSettings показывает администратору группы настройки синтеза.
+func (h *Handler) ChatSettings(c telebot.Context) error {
+	if !isGroup(c) {
+		return c.Send(h.t(c, "chat.group_only"))
+	}
+	if !h.isChatAdmin(c) {
+		return c.Reply(h.t(c, "chat.not_admin"))
+	}
+
+	text, markup, err := h.renderChatSettings(c)
+	if err != nil {
+		h.log.Error("Ошибка получения настроек чата", zap.Error(err))
+		return c.Reply(h.t(c, "error.generic"))
+	}
+	return c.Send(text, markup)
+}
+
+// OnChatAdminOnly включает (Arg = 1) или выключает режим, в котором
+// синтез в группе доступен только администраторам.
+func (h *Handler) OnChatAdminOnly(c telebot.Context, p callback.Payload) error {
+	ctx := requestContext(c)
+	if !h.isChatAdmin(c) {
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "chat.not_admin")})
+	}
+
+	err := h.service.SetChatAdminOnly(ctx, c.Chat().ID, p.Arg == 1)
+	if err != nil {
+		h.log.Error("Ошибка сохранения настроек чата", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "error.generic")})
+	}
+	return h.editChatSettings(c)
+}
+
+// OnChatModel делает текущую модель администратора общей для чата
+// (Arg = 1) или возвращает участникам их собственные модели (Arg = 0).
+func (h *Handler) OnChatModel(c telebot.Context, p callback.Payload) error {
+	ctx := requestContext(c)
+	if !h.isChatAdmin(c) {
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "chat.not_admin")})
+	}
+
+	var modelID int64
+	if p.Arg == 1 {
+		model, err := h.service.GetActiveModel(ctx, c.Sender().ID)
+		if err != nil {
+			if errors.Is(err, defs.ErrNoModel{}) {
+				return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "model.none_active"), ShowAlert: true})
+			}
+			h.log.Error("Ошибка получения активной модели", zap.Error(err))
+			return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "error.generic")})
+		}
+		modelID = model.ID
+	}
+
+	err := h.service.SetChatModel(ctx, c.Chat().ID, c.Sender().ID, modelID)
+	if err != nil {
+		h.log.Error("Ошибка сохранения модели чата", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "error.generic")})
+	}
+	return h.editChatSettings(c)
+}
+
+func (h *Handler) editChatSettings(c telebot.Context) error {
+	text, markup, err := h.renderChatSettings(c)
+	if err != nil {
+		h.log.Error("Ошибка получения настроек чата", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "error.generic")})
+	}
+	return c.Edit(text, markup)
+}
+
+func (h *Handler) renderChatSettings(c telebot.Context) (string, *telebot.ReplyMarkup, error) {
+	ctx := requestContext(c)
+	settings, err := h.service.GetChatSettings(ctx, c.Chat().ID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	kb := h.callbacks.Keyboard(c.Sender().ID)
+	access := h.t(c, "chat.access_all")
+	accessBtn := kb.Btn(h.t(c, "chat.btn_admin_only"), callback.Payload{Action: ActionChatAdminOnly, Arg: 1})
+	if settings.AdminOnly {
+		access = h.t(c, "chat.access_admins")
+		accessBtn = kb.Btn(h.t(c, "chat.btn_everyone"), callback.Payload{Action: ActionChatAdminOnly, Arg: 0})
+	}
+
+	model := h.t(c, "chat.model_own")
+	if settings.Model.ID != 0 {
+		model = h.t(c, "chat.model_shared", settings.Model.Name)
+	}
+
+	markup := &telebot.ReplyMarkup{}
+	markup.Inline(
+		markup.Row(accessBtn),
+		markup.Row(kb.Btn(h.t(c, "chat.btn_use_my_model"), callback.Payload{Action: ActionChatModel, Arg: 1})),
+		markup.Row(kb.Btn(h.t(c, "chat.btn_own_models"), callback.Payload{Action: ActionChatModel, Arg: 0})),
+	)
+	if err := kb.Err(); err != nil {
+		return "", nil, err
+	}
+
+	return h.t(c, "chat.settings", access, model), markup, nil
+}
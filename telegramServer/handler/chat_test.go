@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"testing"
+
+	"gopkg.in/telebot.v3"
+)
+
+func TestCutEntity(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		entity telebot.MessageEntity
+		want   string
+	}{
+		{"в начале", "@voicebot привет", telebot.MessageEntity{Offset: 0, Length: 9}, " привет"},
+		// 😀 занимает две UTF-16 code units.
+		{"после эмодзи", "😀 @voicebot привет", telebot.MessageEntity{Offset: 3, Length: 9}, "😀  привет"},
+		{"после символа вне BMP", "𝔸𝔹 @voicebot", telebot.MessageEntity{Offset: 5, Length: 9}, "𝔸𝔹 "},
+		{"за концом текста", "привет", telebot.MessageEntity{Offset: 10, Length: 9}, "привет"},
+		{"обрезается по концу текста", "привет @voice", telebot.MessageEntity{Offset: 7, Length: 9}, "привет "},
+		{"пустая", "привет", telebot.MessageEntity{Offset: 2, Length: 0}, "привет"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cutEntity(tt.text, tt.entity); got != tt.want {
+				t.Errorf("cutEntity(%q) = %q, ожидалось %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddressedText(t *testing.T) {
+	bot, err := telebot.NewBot(telebot.Settings{Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot.Me = &telebot.User{ID: 42, Username: "VoiceBot", IsBot: true}
+	botUser := &telebot.User{ID: 42}
+	user := &telebot.User{ID: 7}
+
+	mention := func(offset, length int) telebot.Entities {
+		return telebot.Entities{{Type: telebot.EntityMention, Offset: offset, Length: length}}
+	}
+
+	tests := []struct {
+		name      string
+		msg       *telebot.Message
+		want      string
+		addressed bool
+	}{
+		{
+			name:      "упоминание",
+			msg:       &telebot.Message{Text: "@voicebot привет", Entities: mention(0, 9)},
+			want:      "привет",
+			addressed: true,
+		},
+		{
+			name:      "упоминание после эмодзи",
+			msg:       &telebot.Message{Text: "😀😀 @VoiceBot привет", Entities: mention(5, 9)},
+			want:      "😀😀  привет",
+			addressed: true,
+		},
+		{
+			name: "упоминание другого бота",
+			msg:  &telebot.Message{Text: "@otherbot привет", Entities: mention(0, 9)},
+			want: "@otherbot привет",
+		},
+		{
+			name: "без упоминания",
+			msg:  &telebot.Message{Text: "привет"},
+			want: "привет",
+		},
+		{
+			name: "ответ на чужое сообщение без упоминания",
+			msg:  &telebot.Message{Text: "привет", ReplyTo: &telebot.Message{Sender: user, Text: "текст"}},
+			want: "привет",
+		},
+		{
+			name:      "ответ боту без упоминания",
+			msg:       &telebot.Message{Text: "привет", ReplyTo: &telebot.Message{Sender: botUser}},
+			want:      "привет",
+			addressed: true,
+		},
+		{
+			name:      "ответ боту с упоминанием",
+			msg:       &telebot.Message{Text: "привет @voicebot", Entities: mention(7, 9), ReplyTo: &telebot.Message{Sender: botUser}},
+			want:      "привет",
+			addressed: true,
+		},
+		{
+			name:      "упоминание ответом на чужое сообщение",
+			msg:       &telebot.Message{Text: "@voicebot", Entities: mention(0, 9), ReplyTo: &telebot.Message{Sender: user, Text: "😀 текст https://example.com", Entities: telebot.Entities{{Type: telebot.EntityURL, Offset: 9, Length: 19}}}},
+			want:      "😀 текст",
+			addressed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := bot.NewContext(telebot.Update{Message: tt.msg})
+			got, addressed := (&Handler{}).addressedText(c)
+			if got != tt.want || addressed != tt.addressed {
+				t.Errorf("addressedText() = %q, %t, ожидалось %q, %t", got, addressed, tt.want, tt.addressed)
+			}
+		})
+	}
+}
@@ -1,364 +1,529 @@
 package handler
 
 import (
-	"bytes"
+	"context"
 	"errors"
-	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"gopkg.in/telebot.v3"
+	"html"
+	"io"
+	"kursach/book"
+	"kursach/callback"
+	"kursach/client"
 	"kursach/defs"
+	"kursach/i18n"
 	pb "kursach/proto"
-	"os/exec"
+	"kursach/queue"
+	"kursach/tracing"
 	"strconv"
 	"strings"
 )
 
+const (
+	ActionModelsPage callback.Action = iota + 1
+	ActionModelOpen
+	ActionModelSelect
+	ActionModelPreview
+	ActionModelRename
+	ActionModelSample
+	ActionModelDelete
+	ActionModelDeleteConfirm
+	ActionLanguage
+	ActionChatAdminOnly
+	ActionChatModel
+	ActionOutputFormat
+	ActionHistoryPage
+	ActionHistorySend
+	ActionHistoryRegenerate
+	ActionHistorySettings
+	ActionHistoryDays
+	ActionSpeechSlower
+	ActionSpeechFaster
+	ActionSpeechModels
+	ActionSpeechModel
+	ActionSpeechBack
+)
+
+var errTooLarge = errors.New("файл слишком большой для скачивания")
+
 type Service interface {
-	SendAudio(userID int64, text string) (*pb.ProcessingResponse, error)
-	SaveModel(userID int64, fileInfo string, token string, modelName string) error
+	SendAudio(ctx context.Context, userID int64, text string) (*pb.ProcessingResponse, error)
+	SendAudioWithModel(ctx context.Context, userID int64, model defs.Model, text string, speed float64) (*pb.ProcessingResponse, error)
+	Transcribe(ctx context.Context, userID int64, audioData []byte, language string) (string, error)
+	Synthesize(ctx context.Context, userID int64, model defs.Model, text string, speed float64) (defs.Speech, error)
+	Regenerate(ctx context.Context, userID int64, model defs.Model, text string, speed float64) (defs.Speech, error)
+	CachedFileID(key string, format string) (string, bool)
+	CacheFileID(key string, format string, fileID string)
+	SaveModel(ctx context.Context, userID int64, sample []byte, modelName string) error
+
+	SetPendingModel(scope defs.Scope, name string) error
+	GetModelName(scope defs.Scope) (string, error)
+
+	GetUserState(scope defs.Scope) (string, error)
+	SetUserState(scope defs.Scope, state string) error
+
+	GetUserModels(ctx context.Context, userID int64) ([]defs.Model, error)
+	GetModel(ctx context.Context, userID int64, modelID int64) (defs.Model, error)
+	SelectModel(ctx context.Context, userID int64, modelID int64) error
+	GetActiveModel(ctx context.Context, userID int64) (defs.Model, error)
+	ResolveModel(ctx context.Context, scope defs.Scope) (defs.Model, error)
+	GetModelSample(ctx context.Context, userID int64, modelID int64) ([]byte, error)
+
+	CountModels(ctx context.Context, userID int64) (int, error)
+
+	SetEditingModel(scope defs.Scope, modelID int64) error
+	GetEditingModel(scope defs.Scope) (int64, error)
+	RenameModel(ctx context.Context, userID int64, modelID int64, newName string) error
+	AddSample(ctx context.Context, userID int64, modelID int64, sample []byte) error
+	DeleteModel(ctx context.Context, userID int64, modelID int64) error
+
+	GetUserLanguage(ctx context.Context, userID int64) (string, error)
+	SetUserLanguage(ctx context.Context, userID int64, lang string) error
+	GetOutputFormat(ctx context.Context, userID int64) (string, error)
+	SetOutputFormat(ctx context.Context, userID int64, format string) error
+
+	RecordGeneration(ctx context.Context, g defs.Generation) (int64, error)
+	GetGenerations(ctx context.Context, userID int64, page int) ([]defs.Generation, error)
+	CountGenerations(ctx context.Context, userID int64) (int, error)
+	GetGeneration(ctx context.Context, userID int64, generationID int64) (defs.Generation, error)
+	GetHistoryDays(ctx context.Context, userID int64) (int, error)
+	SetHistoryDays(ctx context.Context, userID int64, days int) error
+
+	GetChatSettings(ctx context.Context, chatID int64) (defs.ChatSettings, error)
+	SetChatAdminOnly(ctx context.Context, chatID int64, adminOnly bool) error
+	SetChatModel(ctx context.Context, chatID int64, userID int64, modelID int64) error
+
+	CreateAudiobook(ctx context.Context, scope defs.Scope, lang string, model defs.Model, b *book.Book) (defs.Audiobook, error)
+	GetAudiobook(ctx context.Context, bookID int64) (defs.Audiobook, error)
+	ClaimAudiobooks(ctx context.Context, reclaimOwn bool) ([]int64, error)
+	ClaimAudiobook(ctx context.Context, bookID int64) (bool, error)
+	ReleaseAudiobooks(ctx context.Context) error
+	SetAudiobookMessage(ctx context.Context, bookID int64, messageID int) error
+	SetAudiobookStatus(ctx context.Context, bookID int64, status string) error
+	GetNextChapter(ctx context.Context, bookID int64) (defs.Chapter, bool, error)
+	MarkChapterDone(ctx context.Context, bookID int64, index int) error
+	SynthesizeChapter(ctx context.Context, audiobook defs.Audiobook, chapter defs.Chapter, progress func(done, total int)) ([]byte, int, error)
+}
 
-	SetPendingModel(userID int64, name string) error
-	GetModelName(userID int64) (string, error)
+type Handler struct {
+	service         Service
+	callbacks       *callback.Router
+	queue           *queue.Queue
+	inlineCacheChat int64
+	inlineDebounce  *debouncer
+	audiobooks      *audiobookChains
+	log             *zap.Logger
+}
 
-	GetUserState(userID int64) (string, error)
-	SetUserState(userID int64, state string) error
+func NewHandler(service Service, callbacks *callback.Router, queue *queue.Queue, inlineCacheChat int64, logger *zap.Logger) *Handler {
+	return &Handler{
+		service:         service,
+		callbacks:       callbacks,
+		queue:           queue,
+		inlineCacheChat: inlineCacheChat,
+		inlineDebounce:  newDebouncer(defs.InlineDebounce),
+		audiobooks:      newAudiobookChains(),
+		log:             logger,
+	}
+}
 
-	GetUserModels(userID int64) ([]string, error)
+// Localize определяет язык пользователя для всех обработчиков:
+// выбранный в /language, иначе язык клиента Telegram.
+func (h *Handler) Localize(next telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		sender := c.Sender()
+		if sender == nil {
+			return next(c)
+		}
 
-	CountModels(userID int64) (int, error)
+		lang := sender.LanguageCode
+		saved, err := h.service.GetUserLanguage(requestContext(c), sender.ID)
+		if err != nil {
+			h.log.Warn("Ошибка получения языка пользователя", zap.Int64("userID", sender.ID), zap.Error(err))
+		} else if saved != "" {
+			lang = saved
+		}
 
-	DeleteModel(userID int64, modelName string) error
+		i18n.SetLang(c, i18n.Resolve(lang))
+		return next(c)
+	}
 }
 
-type Handler struct {
-	service Service
-	log     *zap.Logger
+func (h *Handler) t(c telebot.Context, key string, args ...any) string {
+	return i18n.T(i18n.Lang(c), key, args...)
 }
 
-func NewHandler(service Service, logger *zap.Logger) *Handler {
-	return &Handler{
-		service: service,
-		log:     logger,
-	}
+func (h *Handler) n(c telebot.Context, key string, n int, args ...any) string {
+	return i18n.N(i18n.Lang(c), key, n, args...)
 }
 
 func (h *Handler) HandleText(c telebot.Context) error {
+	ctx := requestContext(c)
 	userID := c.Sender().ID
-	h.log.Info("HandleText called", zap.Int64("userID", userID))
+	scope := dialogScope(c)
+	h.log.Info("HandleText called", zap.Int64("userID", userID), zap.Int64("chatID", scope.ChatID))
+
+	text := c.Text()
+	if isGroup(c) {
+		var addressed bool
+		text, addressed = h.addressedText(c)
+		if !addressed {
+			return nil
+		}
+	}
 
-	state, err := h.service.GetUserState(userID)
+	state, err := h.service.GetUserState(scope)
 	if err != nil {
 		h.log.Error("Ошибка получения состояния пользователя", zap.Error(err))
-		return c.Send("Возникла ошибка, повтори попытку позже.")
+		return c.Send(h.t(c, "error.generic"))
 	}
 	h.log.Info("User state retrieved", zap.String("state", state))
 
 	switch state {
 	case defs.WaitingModelName:
-		modelName := c.Text()
+		modelName := text
 		if modelName == "" {
-			return c.Send("Имя модели не может быть пустым.")
+			return c.Send(h.t(c, "model.name_empty"))
+		}
+		if !defs.ValidModelName(modelName) {
+			return c.Send(h.t(c, "model.name_invalid"))
 		}
 
-		models, err := h.service.GetUserModels(userID)
+		models, err := h.service.GetUserModels(ctx, userID)
 		if err != nil {
 			h.log.Error("Ошибка получения моделей пользователя", zap.Error(err))
-			return c.Send("Возникла ошибка, повтори попытку позже.")
+			return c.Send(h.t(c, "error.generic"))
 		}
 
 		for _, model := range models {
-			if modelName == model {
-				return c.Send("У тебя уже есть модель с таким именем.")
+			if modelName == model.Name {
+				return c.Send(h.t(c, "model.name_taken"))
 			}
 		}
 
-		err = h.service.SetPendingModel(userID, modelName)
+		err = h.service.SetPendingModel(scope, modelName)
 		if err != nil {
 			h.log.Error("Ошибка установки PendingModel", zap.Error(err))
-			return c.Send("Возникла ошибка, повтори попытку позже.")
+			return c.Send(h.t(c, "error.generic"))
 		}
 
-		err = h.service.SetUserState(userID, defs.WaitingVoice)
+		err = h.service.SetUserState(scope, defs.WaitingVoice)
 		if err != nil {
 			h.log.Error("Ошибка обновления состояния пользователя", zap.Error(err))
-			return c.Send("Возникла ошибка, повтори попытку позже.")
+			return c.Send(h.t(c, "error.generic"))
 		}
 
 		h.log.Info("Пользователь ввёл имя новой модели", zap.String("modelName", modelName))
-		return c.Send("Пришли голосовое сообщение для создания модели.")
-
-	case defs.WaitingDeleteModelName:
-		modelName := c.Text()
-		if modelName == "" {
-			return c.Send("Имя модели не может быть пустым.")
-		}
+		return c.Send(h.t(c, "model.send_voice"))
 
-		models, err := h.service.GetUserModels(userID)
-		if err != nil {
-			h.log.Error("Ошибка получения моделей пользователя для удаления", zap.Error(err))
-			return c.Send("Возникла ошибка, повтори попытку позже.")
+	case defs.WaitingRenameModelName:
+		newName := text
+		if newName == "" {
+			return c.Send(h.t(c, "model.name_empty"))
 		}
-
-		found := false
-		for _, model := range models {
-			if modelName == model {
-				found = true
-				break
-			}
+		if !defs.ValidModelName(newName) {
+			return c.Send(h.t(c, "model.name_invalid"))
 		}
 
-		if !found {
-			return c.Send("Модель с таким именем не найдена.")
+		modelID, err := h.service.GetEditingModel(scope)
+		if err != nil {
+			h.log.Error("Ошибка получения редактируемой модели", zap.Error(err))
+			_ = h.service.SetUserState(scope, defs.FreeState)
+			return c.Send(h.t(c, "model.not_found"))
 		}
 
-		err = h.service.DeleteModel(userID, modelName)
+		models, err := h.service.GetUserModels(ctx, userID)
 		if err != nil {
-			h.log.Error("Ошибка удаления модели", zap.Error(err))
-			return c.Send("Ошибка при удалении модели.")
+			h.log.Error("Ошибка получения моделей пользователя", zap.Error(err))
+			return c.Send(h.t(c, "error.generic"))
 		}
 
-		h.log.Info("Модель успешно удалена", zap.String("modelName", modelName))
-		_ = h.service.SetUserState(userID, defs.FreeState)
-		return c.Send("Модель успешно удалена.")
-
-	default:
-		text := c.Text()
-		audioRes, err := h.service.SendAudio(userID, text)
-		if err != nil {
-			if errors.Is(err, defs.ErrNoModel{}) {
-				return c.Send("Создай модель /save_model или выбери из сохранённых /choose_model.")
+		for _, model := range models {
+			if newName == model.Name && model.ID != modelID {
+				return c.Send(h.t(c, "model.name_taken"))
 			}
-			h.log.Error("Ошибка генерации аудио", zap.Error(err))
-			return c.Send("Возникла ошибка, повтори попытку позже.")
 		}
 
-		ogg, dur, err := EnsureVoiceNOTE(audioRes.Result.ProcessedAudio)
+		err = h.service.RenameModel(ctx, userID, modelID, newName)
 		if err != nil {
-			h.log.Error("Ошибка перекодировки аудио", zap.Error(err))
-			return c.Send("Возникла ошибка, повтори попытку позже.")
+			h.log.Error("Ошибка переименования модели", zap.Error(err))
+			return c.Send(h.t(c, "model.rename_failed"))
 		}
 
-		voiceMsg := &telebot.Voice{
-			File: telebot.File{
-				FileReader: bytes.NewReader(ogg),
-			},
-			MIME:     "audio/ogg",
-			Duration: dur,
-		}
+		_ = h.service.SetUserState(scope, defs.FreeState)
+		h.log.Info("Модель переименована", zap.String("newName", newName))
+		_ = c.Send(h.t(c, "model.renamed", newName))
+		return h.ShowModels(c)
 
-		h.log.Info("Отправка голосового сообщения пользователю", zap.Int64("userID", userID))
-		return c.Send(voiceMsg)
+	default:
+		return h.synthesize(c, text)
 	}
 }
 
+// HandleVoice принимает голосовое, аудио, видео или видеосообщение:
+// образец для новой модели, дополнение к образцу или, в свободном
+// состоянии, речь для пересказа голосом активной модели.
 func (h *Handler) HandleVoice(c telebot.Context) error {
+	ctx := requestContext(c)
 	userID := c.Sender().ID
-	h.log.Info("HandleVoice called", zap.Int64("userID", userID))
+	scope := dialogScope(c)
+	h.log.Info("HandleVoice called", zap.Int64("userID", userID), zap.Int64("chatID", scope.ChatID))
 
-	state, err := h.service.GetUserState(userID)
+	state, err := h.service.GetUserState(scope)
 	if err != nil {
 		h.log.Error("Ошибка получения состояния пользователя", zap.Error(err))
-		return c.Send("Возникла ошибка, повтори попытку позже.")
+		return c.Send(h.t(c, "error.generic"))
+	}
+
+	if state == defs.WaitingSample {
+		return h.addSample(c)
 	}
 
 	if state != defs.WaitingVoice {
-		return c.Send("Я не жду голосовое сообщение. Сохрани модель через /save_model")
+		if isGroup(c) {
+			return nil
+		}
+		return h.revoice(c)
 	}
 
-	modelName, err := h.service.GetModelName(userID)
+	modelName, err := h.service.GetModelName(scope)
 	if err != nil {
 		h.log.Error("Ошибка получения имени модели", zap.Error(err))
-		return c.Send("Имя модели не найдено.")
+		return c.Send(h.t(c, "model.pending_missing"))
 	}
 	h.log.Info("Получено имя модели для сохранения", zap.String("modelName", modelName))
 
-	fileInfo, err := c.Bot().FileByID(c.Message().Voice.FileID)
+	sample, err := h.downloadMedia(c)
 	if err != nil {
-		h.log.Error("Ошибка получения файла по ID", zap.Error(err))
-		return c.Send("Возникла ошибка, повтори попытку позже.")
+		return h.sendDownloadError(c, err)
 	}
 
-	err = h.service.SaveModel(userID, fileInfo.FilePath, c.Bot().Token, modelName)
+	err = h.service.SaveModel(ctx, userID, sample, modelName)
 	if err != nil {
 		h.log.Error("Ошибка сохранения модели", zap.Error(err))
-		return c.Send("Возникла ошибка, повтори попытку позже.")
+		return c.Send(h.t(c, "error.generic"))
 	}
 
-	err = h.service.SetUserState(userID, defs.FreeState)
+	err = h.service.SetUserState(scope, defs.FreeState)
 	if err != nil {
 		h.log.Error("Ошибка сброса состояния пользователя", zap.Error(err))
-		return c.Send("Возникла ошибка, повтори попытку позже.")
+		return c.Send(h.t(c, "error.generic"))
 	}
 
 	h.log.Info("Модель успешно сохранена", zap.Int64("userID", userID))
-	return c.Send("Модель успешно сохранена.")
+	return c.Send(h.t(c, "model.saved", modelName))
+}
+
+// revoice распознаёт речь в сообщении и озвучивает распознанный
+// текст активной моделью. Текст показывается пользователю: исправленный
+// вариант можно прислать обычным сообщением, и он будет озвучен заново.
+func (h *Handler) revoice(c telebot.Context) error {
+	_ = c.Notify(telebot.Typing)
+
+	data, err := h.downloadMedia(c)
+	if err != nil {
+		return h.sendDownloadError(c, err)
+	}
+
+	text, err := h.service.Transcribe(requestContext(c), c.Sender().ID, data, "")
+	if err != nil {
+		h.log.Error("Ошибка распознавания речи", zap.Error(err))
+		return c.Send(h.failureText(c, err))
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return c.Send(h.t(c, "voice.no_speech"))
+	}
+
+	err = c.Send(h.t(c, "voice.recognized", html.EscapeString(text)), telebot.ModeHTML)
+	if err != nil {
+		return err
+	}
+	return h.synthesize(c, text)
+}
+
+// downloadMedia скачивает голосовое, аудио или видео из сообщения.
+func (h *Handler) downloadMedia(c telebot.Context) ([]byte, error) {
+	file := mediaFile(c.Message())
+	if file == nil {
+		return nil, errors.New("в сообщении нет голосового, аудио или видео")
+	}
+	if file.FileSize > defs.MaxDownloadSize {
+		return nil, errTooLarge
+	}
+	return h.downloadFile(c, file)
+}
+
+// failureText — сообщение об ошибке синтеза или распознавания. Если
+// AudioProcessor недоступен, пользователь узнаёт, что стоит повторить
+// позже.
+func (h *Handler) failureText(c telebot.Context, err error) string {
+	if errors.Is(err, defs.ErrServiceBusy{}) {
+		return h.t(c, "error.busy")
+	}
+	return h.t(c, "error.generic")
+}
+
+// requestContext возвращает контекст обработки обновления: со спаном
+// обновления (см. TraceUpdates) и ID запроса из ID обновления Telegram,
+// чтобы по логам бота и сервера можно было проследить обработку
+// сообщения пользователя.
+func requestContext(c telebot.Context) context.Context {
+	ctx, ok := c.Get(updateContextKey).(context.Context)
+	if !ok {
+		ctx = context.Background()
+	}
+	return client.WithRequestID(ctx, "upd-"+strconv.Itoa(c.Update().ID))
+}
+
+func (h *Handler) sendDownloadError(c telebot.Context, err error) error {
+	if errors.Is(err, errTooLarge) {
+		return c.Send(h.t(c, "file.too_large", defs.MaxDownloadSize>>20))
+	}
+	h.log.Error("Ошибка загрузки файла", zap.Error(err))
+	return c.Send(h.t(c, "error.generic"))
+}
+
+func (h *Handler) downloadFile(c telebot.Context, file *telebot.File) (_ []byte, err error) {
+	_, span := tracer.Start(requestContext(c), "telegram.download", trace.WithAttributes(attribute.Int64("telegram.file_size", file.FileSize)))
+	defer func() { tracing.End(span, err) }()
+
+	reader, err := c.Bot().File(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(io.LimitReader(reader, defs.MaxDownloadSize))
+}
+
+func (h *Handler) addSample(c telebot.Context) error {
+	ctx := requestContext(c)
+	userID := c.Sender().ID
+	scope := dialogScope(c)
+
+	modelID, err := h.service.GetEditingModel(scope)
+	if err != nil {
+		h.log.Error("Ошибка получения редактируемой модели", zap.Error(err))
+		_ = h.service.SetUserState(scope, defs.FreeState)
+		return c.Send(h.t(c, "model.not_found"))
+	}
+
+	sample, err := h.downloadMedia(c)
+	if err != nil {
+		return h.sendDownloadError(c, err)
+	}
+
+	err = h.service.AddSample(ctx, userID, modelID, sample)
+	if err != nil {
+		h.log.Error("Ошибка добавления образца", zap.Error(err))
+		return c.Send(h.t(c, "error.generic"))
+	}
+
+	err = h.service.SetUserState(scope, defs.FreeState)
+	if err != nil {
+		h.log.Error("Ошибка сброса состояния пользователя", zap.Error(err))
+		return c.Send(h.t(c, "error.generic"))
+	}
+
+	return c.Send(h.t(c, "sample.added"))
 }
 
 func (h *Handler) GetModelName(c telebot.Context) error {
+	ctx := requestContext(c)
 	userID := c.Sender().ID
 	h.log.Info("GetModelName called", zap.Int64("userID", userID))
 
-	command := c.Message().Text
+	command := commandName(c.Message().Text)
 
 	switch command {
 	case "/save_model":
-		count, err := h.service.CountModels(userID)
+		count, err := h.service.CountModels(ctx, userID)
 		if err != nil {
 			h.log.Error("Ошибка подсчёта моделей", zap.Error(err))
-			return c.Send("Возникла ошибка, повтори попытку позже.")
+			return c.Send(h.t(c, "error.generic"))
 		}
 		if count >= defs.MaxModels {
-			return c.Send("Превышен лимит количества моделей.")
+			return c.Send(h.n(c, "model.limit", defs.MaxModels, defs.MaxModels))
 		}
-		err = h.service.SetUserState(userID, defs.WaitingModelName)
+		err = h.service.SetUserState(dialogScope(c), defs.WaitingModelName)
 		if err != nil {
 			h.log.Error("Ошибка установки состояния ожидания имени модели", zap.Error(err))
 			return err
 		}
-	case "/delete_model":
-		err := h.service.SetUserState(userID, defs.WaitingDeleteModelName)
-		if err != nil {
-			h.log.Error("Ошибка установки состояния ожидания удаления модели", zap.Error(err))
-			return err
-		}
 	default:
 		h.log.Warn("Неизвестная команда в GetModelName", zap.String("command", command))
-		return c.Send("Неизвестная команда.")
+		return c.Send(h.t(c, "command.unknown"))
 	}
 
-	return c.Send("Введи имя модели:")
+	return c.Send(h.t(c, "model.ask_name"))
 }
 
-func (h *Handler) GetUserModels(c telebot.Context) error {
+func (h *Handler) Current(c telebot.Context) error {
+	ctx := requestContext(c)
 	userID := c.Sender().ID
-	h.log.Info("GetUserModels called", zap.Int64("userID", userID))
+	h.log.Info("Current called", zap.Int64("userID", userID))
 
-	models, err := h.service.GetUserModels(userID)
+	model, err := h.service.GetActiveModel(ctx, userID)
 	if err != nil {
-		h.log.Error("Ошибка получения списка моделей", zap.Error(err))
-		_ = c.Send("Ошибка при получении моделей.")
-		return err
-	}
-	if len(models) == 0 {
-		return c.Send("Пока нет сохранённых моделей.")
+		if errors.Is(err, defs.ErrNoModel{}) {
+			return c.Send(h.t(c, "model.none_active"))
+		}
+		h.log.Error("Ошибка получения активной модели", zap.Error(err))
+		return c.Send(h.t(c, "error.generic"))
 	}
 
+	return c.Send(h.t(c, "model.current", model.Name))
+}
+
+func (h *Handler) ChooseLanguage(c telebot.Context) error {
+	kb := h.callbacks.Keyboard(c.Sender().ID)
 	markup := &telebot.ReplyMarkup{}
-	rows := make([]telebot.Row, 0, len(models))
 
-	for _, name := range models {
-		btn := markup.Data(name, "choose_model", name)
+	rows := []telebot.Row{
+		markup.Row(kb.Btn(h.t(c, "language.auto"), callback.Payload{Action: ActionLanguage})),
+	}
+	for i, lang := range i18n.Languages {
+		btn := kb.Btn(i18n.T(lang, "language.name"), callback.Payload{Action: ActionLanguage, Arg: int64(i + 1)})
 		rows = append(rows, markup.Row(btn))
 	}
+	if err := kb.Err(); err != nil {
+		h.log.Error("Ошибка создания кнопок выбора языка", zap.Error(err))
+		return c.Send(h.t(c, "error.generic"))
+	}
 	markup.Inline(rows...)
 
-	h.log.Info("Отправка списка моделей пользователю", zap.Int64("userID", userID))
-	return c.Send("Выбери модель:", markup)
+	return c.Send(h.t(c, "language.choose"), markup)
 }
 
-func (h *Handler) OnChooseModel(c telebot.Context) error {
+// OnLanguage сохраняет выбранный язык. Arg — номер языка в
+// i18n.Languages, начиная с 1; 0 означает язык клиента Telegram.
+func (h *Handler) OnLanguage(c telebot.Context, p callback.Payload) error {
+	ctx := requestContext(c)
 	userID := c.Sender().ID
-	data := strings.Split(c.Callback().Data, "|")
-	if len(data) < 2 {
-		h.log.Warn("Неверный формат callback данных", zap.String("data", c.Callback().Data))
-		return c.Send("Некорректный выбор модели.")
+
+	lang := ""
+	if p.Arg > 0 && int(p.Arg) <= len(i18n.Languages) {
+		lang = i18n.Languages[p.Arg-1]
 	}
-	modelName := data[1]
 
-	err := h.service.SetPendingModel(userID, modelName)
+	err := h.service.SetUserLanguage(ctx, userID, lang)
 	if err != nil {
-		h.log.Error("Ошибка выбора модели", zap.Error(err))
-		return c.Send("Возникла ошибка, повтори попытку позже.")
+		h.log.Error("Ошибка сохранения языка", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "error.generic")})
 	}
 
-	err = c.Respond(&telebot.CallbackResponse{
-		Text:      "Выбрана модель: " + modelName,
-		ShowAlert: false,
-	})
-	if err != nil {
-		h.log.Error("Ошибка ответа на callback", zap.Error(err))
-		return err
+	if lang == "" {
+		i18n.SetLang(c, i18n.Resolve(c.Sender().LanguageCode))
+		return c.Edit(h.t(c, "language.reset"))
 	}
 
-	h.log.Info("Пользователь выбрал модель", zap.String("modelName", modelName), zap.Int64("userID", userID))
-	return c.Send("Модель \"" + modelName + "\" выбрана.")
+	i18n.SetLang(c, lang)
+	return c.Edit(h.t(c, "language.set"))
 }
 
 func (h *Handler) Start(c telebot.Context) error {
-	text := `Привет! 👋
-
-Я помогу тебе создавать голосовые модели и генерировать аудио.
-
-Вот что ты можешь сделать:
-  
-/save_model — создать новую голосовую модель
-/choose_model — выбрать одну из сохранённых моделей
-/start — показать эту инструкцию ещё раз
-
-⚡ Просто напиши мне текст — я озвучу его выбранной моделью!`
-	return c.Send(text)
-}
-
-func EnsureVoiceNOTE(in []byte) (outBytes []byte, durationSec int, err error) {
-	const ffprobeFmt = "default=noprint_wrappers=1:nokey=1"
-
-	infoCmd := exec.Command("ffprobe",
-		"-v", "error",
-		"-select_streams", "a:0",
-		"-show_entries", "stream=codec_name,channels,sample_rate",
-		"-of", ffprobeFmt,
-		"pipe:0",
-	)
-	infoCmd.Stdin = bytes.NewReader(in)
-	infoOut, _ := infoCmd.Output()
-
-	needRecode := true
-	if len(infoOut) > 0 {
-		fields := strings.Split(strings.TrimSpace(string(infoOut)), "\n")
-		if len(fields) == 3 &&
-			fields[0] == "opus" &&
-			fields[1] == "1" &&
-			fields[2] == "48000" {
-			needRecode = false
-		}
-	}
-
-	var ogg bytes.Buffer
-	if !needRecode {
-		ogg.Write(in)
-	} else {
-		ff := exec.Command("ffmpeg",
-			"-loglevel", "error",
-			"-i", "pipe:0",
-			"-c:a", "libopus",
-			"-application", "voip",
-			"-b:a", "64k",
-			"-ac", "1",
-			"-ar", "48000",
-			"-f", "ogg",
-			"pipe:1",
-		)
-		ff.Stdin = bytes.NewReader(in)
-		ff.Stdout = &ogg
-
-		if err = ff.Run(); err != nil {
-			return nil, 0, fmt.Errorf("ffmpeg recode: %w", err)
-		}
-	}
-
-	pr := exec.Command("ffprobe",
-		"-v", "error",
-		"-show_entries", "format=duration",
-		"-of", ffprobeFmt,
-		"pipe:0",
-	)
-	pr.Stdin = bytes.NewReader(ogg.Bytes())
-
-	durOut, err := pr.Output()
-	if err != nil {
-		return nil, 0, errors.New("ffprobe: " + err.Error())
-	}
-	dur, _ := strconv.ParseFloat(strings.TrimSpace(string(durOut)), 64)
-
-	return ogg.Bytes(), int(dur + 0.5), nil
+	return c.Send(h.t(c, "start.text"))
 }
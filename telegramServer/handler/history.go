@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"gopkg.in/telebot.v3"
+	"kursach/callback"
+	"kursach/defs"
+	"strings"
+)
+
+const historyTimeLayout = "02.01 15:04"
+
+// ShowHistory отправляет историю озвучки пользователя. Дальше сообщение
+// редактируется на месте кнопками листания и настройки.
+func (h *Handler) ShowHistory(c telebot.Context) error {
+	// История личная и в группах не показывается.
+	if isGroup(c) {
+		return nil
+	}
+
+	h.log.Info("ShowHistory called", zap.Int64("userID", c.Sender().ID))
+	text, markup, err := h.renderHistory(c, 0)
+	if err != nil {
+		h.log.Error("Ошибка получения истории озвучки", zap.Error(err))
+		return c.Send(h.t(c, "error.generic"))
+	}
+	return c.Send(text, markup)
+}
+
+func (h *Handler) OnHistoryPage(c telebot.Context, p callback.Payload) error {
+	text, markup, err := h.renderHistory(c, int(p.Arg))
+	if err != nil {
+		h.log.Error("Ошибка получения истории озвучки", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "error.generic")})
+	}
+	return c.Edit(text, markup)
+}
+
+// OnHistorySend отправляет запись истории повторно по file_id, без
+// синтеза и загрузки файла. Arg — ID записи.
+func (h *Handler) OnHistorySend(c telebot.Context, p callback.Payload) error {
+	ctx := requestContext(c)
+	userID := c.Sender().ID
+	g, err := h.service.GetGeneration(ctx, userID, p.Arg)
+	if err != nil {
+		return h.respondGenerationError(c, err)
+	}
+
+	what := speechFile(g.Params.Format, telebot.File{FileID: g.FileID}, g.Model, g.Text, g.Duration)
+	if err := c.Send(what); err != nil {
+		h.log.Error("Ошибка повторной отправки озвучки", zap.Int64("generationID", g.ID), zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "error.generic")})
+	}
+	return c.Respond()
+}
+
+// OnHistoryRegenerate озвучивает текст записи заново той же моделью и
+// с теми же параметрами. Кнопка есть в истории и под каждой озвучкой.
+// Arg — ID записи.
+func (h *Handler) OnHistoryRegenerate(c telebot.Context, p callback.Payload) error {
+	ctx := requestContext(c)
+	g, err := h.service.GetGeneration(ctx, c.Sender().ID, p.Arg)
+	if err != nil {
+		return h.respondGenerationError(c, err)
+	}
+
+	model, err := h.generationModel(c, g)
+	if err != nil {
+		return h.respondGenerationModelError(c, g, err)
+	}
+	return h.revoiceGeneration(c, g, model, g.Params.Speed, true)
+}
+
+// OnHistorySettings показывает выбор срока хранения истории.
+func (h *Handler) OnHistorySettings(c telebot.Context, _ callback.Payload) error {
+	ctx := requestContext(c)
+	userID := c.Sender().ID
+	current, err := h.service.GetHistoryDays(ctx, userID)
+	if err != nil {
+		h.log.Error("Ошибка получения срока хранения истории", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "error.generic")})
+	}
+
+	kb := h.callbacks.Keyboard(userID)
+	markup := &telebot.ReplyMarkup{}
+	rows := make([]telebot.Row, 0, len(defs.HistoryDays)+1)
+	for i, days := range defs.HistoryDays {
+		text := h.historyDaysLabel(c, days)
+		if days == current {
+			text = "✅ " + text
+		}
+		rows = append(rows, markup.Row(kb.Btn(text, callback.Payload{Action: ActionHistoryDays, Arg: int64(i + 1)})))
+	}
+	rows = append(rows, markup.Row(historyPageBtn(kb, h.t(c, "history.btn_back"), 0)))
+	if err := kb.Err(); err != nil {
+		return err
+	}
+	markup.Inline(rows...)
+
+	return c.Edit(h.t(c, "history.days_choose"), markup)
+}
+
+// OnHistoryDays сохраняет срок хранения истории. Arg — номер срока в
+// defs.HistoryDays, начиная с 1.
+func (h *Handler) OnHistoryDays(c telebot.Context, p callback.Payload) error {
+	ctx := requestContext(c)
+	if p.Arg < 1 || int(p.Arg) > len(defs.HistoryDays) {
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "callback.forged")})
+	}
+	days := defs.HistoryDays[p.Arg-1]
+
+	err := h.service.SetHistoryDays(ctx, c.Sender().ID, days)
+	if err != nil {
+		h.log.Error("Ошибка сохранения срока хранения истории", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "error.generic")})
+	}
+
+	_ = c.Respond(&telebot.CallbackResponse{Text: h.t(c, "history.days_set", h.historyDaysLabel(c, days))})
+	return h.OnHistoryPage(c, callback.Payload{})
+}
+
+// respondGenerationError отвечает на нажатие кнопки записи, которой
+// уже нет: она удалена по сроку хранения.
+func (h *Handler) respondGenerationError(c telebot.Context, err error) error {
+	if errors.Is(err, defs.ErrNoGeneration{}) {
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "history.not_found")})
+	}
+	h.log.Error("Ошибка получения записи истории", zap.Error(err))
+	return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "error.generic")})
+}
+
+func (h *Handler) renderHistory(c telebot.Context, page int) (string, *telebot.ReplyMarkup, error) {
+	ctx := requestContext(c)
+	userID := c.Sender().ID
+	days, err := h.service.GetHistoryDays(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	total, err := h.service.CountGenerations(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	kb := h.callbacks.Keyboard(userID)
+	markup := &telebot.ReplyMarkup{}
+	settings := markup.Row(kb.Btn(h.t(c, "history.btn_settings"), callback.Payload{Action: ActionHistorySettings}))
+	if err := kb.Err(); err != nil {
+		return "", nil, err
+	}
+
+	if days == 0 {
+		markup.Inline(settings)
+		return h.t(c, "history.off"), markup, nil
+	}
+	if total == 0 {
+		markup.Inline(settings)
+		return h.n(c, "history.empty", days, days), markup, nil
+	}
+
+	pages := (total + defs.GenerationsPerPage - 1) / defs.GenerationsPerPage
+	page = max(min(page, pages-1), 0)
+
+	generations, err := h.service.GetGenerations(ctx, userID, page)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var text strings.Builder
+	text.WriteString(h.n(c, "history.title", days, days))
+	rows := make([]telebot.Row, 0, len(generations)+2)
+	for i, g := range generations {
+		n := page*defs.GenerationsPerPage + i + 1
+		text.WriteString("\n\n")
+		text.WriteString(h.t(c, "history.entry", n, g.CreatedAt.Format(historyTimeLayout), g.Model.Name, g.Duration, speechTitle(g.Text)))
+
+		rows = append(rows, markup.Row(
+			kb.Btn(h.t(c, "history.btn_send", n), callback.Payload{Action: ActionHistorySend, Arg: g.ID}),
+			kb.Btn(h.t(c, "history.btn_regenerate", n), callback.Payload{Action: ActionHistoryRegenerate, Arg: g.ID}),
+		))
+	}
+
+	if pages > 1 {
+		var nav telebot.Row
+		if page > 0 {
+			nav = append(nav, historyPageBtn(kb, "◀️", page-1))
+		}
+		nav = append(nav, historyPageBtn(kb, fmt.Sprintf("%d/%d", page+1, pages), page))
+		if page < pages-1 {
+			nav = append(nav, historyPageBtn(kb, "▶️", page+1))
+		}
+		rows = append(rows, nav)
+	}
+	rows = append(rows, settings)
+	if err := kb.Err(); err != nil {
+		return "", nil, err
+	}
+	markup.Inline(rows...)
+
+	return text.String(), markup, nil
+}
+
+func (h *Handler) historyDaysLabel(c telebot.Context, days int) string {
+	if days == 0 {
+		return h.t(c, "history.days_off")
+	}
+	return h.n(c, "history.days", days, days)
+}
+
+func historyPageBtn(kb *callback.Keyboard, text string, page int) telebot.Btn {
+	return kb.Btn(text, callback.Payload{Action: ActionHistoryPage, Arg: int64(page)})
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"go.uber.org/zap"
+	"gopkg.in/telebot.v3"
+	"kursach/defs"
+	"strings"
+	"sync"
+	"time"
+)
+
+// debouncer пропускает только последний запрос пользователя, после
+// которого delay не приходило новых.
+type debouncer struct {
+	mu    sync.Mutex
+	delay time.Duration
+	seq   map[int64]uint64
+}
+
+func newDebouncer(delay time.Duration) *debouncer {
+	return &debouncer{
+		delay: delay,
+		seq:   make(map[int64]uint64),
+	}
+}
+
+func (d *debouncer) wait(userID int64) bool {
+	d.mu.Lock()
+	d.seq[userID]++
+	current := d.seq[userID]
+	d.mu.Unlock()
+
+	time.Sleep(d.delay)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.seq[userID] != current {
+		return false
+	}
+	delete(d.seq, userID)
+	return true
+}
+
+// OnInlineQuery синтезирует текст inline-запроса "@bot текст" активной
+// моделью пользователя. Готовое голосовое загружается в служебный чат,
+// чтобы получить file_id, который хранится в кэше синтеза.
+func (h *Handler) OnInlineQuery(c telebot.Context) error {
+	ctx := requestContext(c)
+	userID := c.Sender().ID
+	text := strings.TrimSpace(c.Query().Text)
+	if text == "" {
+		return c.Answer(h.inlineHint(c, "inline.hint"))
+	}
+
+	if h.inlineCacheChat == 0 {
+		return c.Answer(h.inlineHint(c, "inline.disabled"))
+	}
+
+	if !h.inlineDebounce.wait(userID) {
+		return nil
+	}
+
+	model, err := h.service.GetActiveModel(ctx, userID)
+	if err != nil {
+		if errors.Is(err, defs.ErrNoModel{}) {
+			return c.Answer(h.inlineHint(c, "inline.no_model"))
+		}
+		h.log.Error("Ошибка получения активной модели", zap.Error(err))
+		return err
+	}
+
+	h.log.Info("Inline синтез", zap.Int64("userID", userID), zap.String("modelName", model.Name))
+	speech, err := h.service.Synthesize(ctx, userID, model, text, defs.NormalSpeed)
+	if err != nil {
+		h.log.Error("Ошибка синтеза для inline-запроса", zap.Error(err))
+		if errors.Is(err, defs.ErrServiceBusy{}) {
+			return c.Answer(h.inlineHint(c, "inline.busy"))
+		}
+		return c.Answer(h.inlineHint(c, "inline.failed"))
+	}
+
+	fileID, ok := h.service.CachedFileID(speech.Key, defs.OutputVoice)
+	if !ok {
+		fileID, err = h.uploadInlineVoice(c, speech)
+		if err != nil {
+			h.log.Error("Ошибка загрузки голосового для inline-запроса", zap.Error(err))
+			return c.Answer(h.inlineHint(c, "inline.failed"))
+		}
+		h.service.CacheFileID(speech.Key, defs.OutputVoice, fileID)
+	}
+
+	result := &telebot.VoiceResult{
+		Cache: fileID,
+		Title: model.Name + ": " + text,
+	}
+	result.SetResultID(speech.Key[:32])
+
+	return c.Answer(&telebot.QueryResponse{
+		Results:    telebot.Results{result},
+		CacheTime:  300,
+		IsPersonal: true,
+	})
+}
+
+// uploadInlineVoice загружает голосовое в служебный чат: inline-ответ
+// может ссылаться только на файл, уже загруженный в Telegram.
+func (h *Handler) uploadInlineVoice(c telebot.Context, speech defs.Speech) (string, error) {
+	msg, err := c.Bot().Send(&telebot.Chat{ID: h.inlineCacheChat}, &telebot.Voice{
+		File:     telebot.File{FileReader: bytes.NewReader(speech.Opus)},
+		MIME:     "audio/ogg",
+		Duration: speech.Duration,
+	})
+	if err != nil {
+		return "", err
+	}
+	if msg.Voice == nil {
+		return "", errors.New("в ответе Telegram нет голосового сообщения")
+	}
+	return msg.Voice.FileID, nil
+}
+
+func (h *Handler) inlineHint(c telebot.Context, key string) *telebot.QueryResponse {
+	return &telebot.QueryResponse{
+		Results:           telebot.Results{},
+		CacheTime:         1,
+		IsPersonal:        true,
+		SwitchPMText:      h.t(c, key),
+		SwitchPMParameter: "inline",
+	}
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/telebot.v3"
+	"strings"
+	"time"
+)
+
+var (
+	updatesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "telegram_updates_total",
+		Help: "Обновления Telegram по типам и командам.",
+	}, []string{"type", "command"})
+
+	updateDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "telegram_update_duration_seconds",
+		Help: "Длительность обработки обновлений Telegram по типам.",
+		// Обработчик ждёт синтеза, поэтому доходит до минут.
+		Buckets: prometheus.ExponentialBuckets(0.01, 3, 11),
+	}, []string{"type"})
+)
+
+func init() {
+	prometheus.MustRegister(updatesTotal, updateDuration)
+}
+
+// ObserveUpdates считает обновления и время их обработки. Команды вне
+// commands учитываются как "other", чтобы произвольный текст после "/"
+// не порождал новых меток.
+func ObserveUpdates(commands ...string) telebot.MiddlewareFunc {
+	known := make(map[string]bool, len(commands))
+	for _, command := range commands {
+		known["/"+command] = true
+	}
+
+	return func(next telebot.HandlerFunc) telebot.HandlerFunc {
+		return func(c telebot.Context) error {
+			update := c.Update()
+			kind := updateType(update)
+
+			command := updateCommand(update)
+			if command != "" && !known[command] {
+				command = "other"
+			}
+			updatesTotal.WithLabelValues(kind, command).Inc()
+
+			start := time.Now()
+			defer func() {
+				updateDuration.WithLabelValues(kind).Observe(time.Since(start).Seconds())
+			}()
+			return next(c)
+		}
+	}
+}
+
+// updateCommand возвращает команду сообщения без имени бота, например,
+// "/say", или пустую строку.
+func updateCommand(u telebot.Update) string {
+	if u.Message == nil || !strings.HasPrefix(u.Message.Text, "/") {
+		return ""
+	}
+	command, _, _ := strings.Cut(strings.Fields(u.Message.Text)[0], "@")
+	return command
+}
+
+// updateType возвращает тип обновления, для сообщений — тип
+// содержимого.
+func updateType(u telebot.Update) string {
+	switch {
+	case u.Message != nil:
+		m := u.Message
+		switch {
+		case m.Text != "":
+			return "text"
+		case m.Voice != nil:
+			return "voice"
+		case m.Audio != nil:
+			return "audio"
+		case m.VideoNote != nil:
+			return "video_note"
+		case m.Video != nil:
+			return "video"
+		case m.Document != nil:
+			return "document"
+		}
+		return "message"
+	case u.EditedMessage != nil:
+		return "edited_message"
+	case u.Callback != nil:
+		return "callback"
+	case u.Query != nil:
+		return "inline_query"
+	case u.InlineResult != nil:
+		return "inline_result"
+	case u.MyChatMember != nil, u.ChatMember != nil:
+		return "chat_member"
+	}
+	return "other"
+}
@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"gopkg.in/telebot.v3"
+	"kursach/audio"
+	"kursach/callback"
+	"kursach/defs"
+)
+
+// ShowModels отправляет сообщение менеджера моделей. Дальше оно
+// редактируется на месте обработчиками кнопок.
+func (h *Handler) ShowModels(c telebot.Context) error {
+	userID := c.Sender().ID
+	h.log.Info("ShowModels called", zap.Int64("userID", userID))
+
+	text, markup, err := h.renderModelList(c, 0)
+	if err != nil {
+		h.log.Error("Ошибка получения списка моделей", zap.Error(err))
+		return c.Send(h.t(c, "models.load_failed"))
+	}
+
+	return c.Send(text, markup)
+}
+
+func (h *Handler) OnModelsPage(c telebot.Context, p callback.Payload) error {
+	text, markup, err := h.renderModelList(c, int(p.Arg))
+	if err != nil {
+		h.log.Error("Ошибка получения списка моделей", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "models.load_failed")})
+	}
+
+	return c.Edit(text, markup)
+}
+
+func (h *Handler) OnModelOpen(c telebot.Context, p callback.Payload) error {
+	model, page, err := h.callbackModel(c, p)
+	if err != nil {
+		return h.respondModelError(c, err, page)
+	}
+
+	text, markup, err := h.renderModelCard(c, model, page, h.isActiveModel(requestContext(c), c.Sender().ID, model.ID))
+	if err != nil {
+		return err
+	}
+	return c.Edit(text, markup)
+}
+
+func (h *Handler) OnModelSelect(c telebot.Context, p callback.Payload) error {
+	ctx := requestContext(c)
+	userID := c.Sender().ID
+	model, page, err := h.callbackModel(c, p)
+	if err != nil {
+		return h.respondModelError(c, err, page)
+	}
+
+	err = h.service.SelectModel(ctx, userID, model.ID)
+	if err != nil {
+		h.log.Error("Ошибка выбора модели", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "error.generic")})
+	}
+
+	h.log.Info("Пользователь выбрал модель", zap.String("modelName", model.Name), zap.Int64("userID", userID))
+	_ = c.Respond(&telebot.CallbackResponse{Text: h.t(c, "models.selected", model.Name)})
+
+	text, markup, err := h.renderModelCard(c, model, page, true)
+	if err != nil {
+		return err
+	}
+	return c.Edit(text, markup)
+}
+
+func (h *Handler) OnModelPreview(c telebot.Context, p callback.Payload) error {
+	ctx := requestContext(c)
+	userID := c.Sender().ID
+	model, page, err := h.callbackModel(c, p)
+	if err != nil {
+		return h.respondModelError(c, err, page)
+	}
+
+	sample, err := h.service.GetModelSample(ctx, userID, model.ID)
+	if err != nil {
+		h.log.Error("Ошибка получения образца модели", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "models.sample_failed")})
+	}
+
+	ogg, dur, err := audio.ToVoice(ctx, sample)
+	if err != nil {
+		h.log.Error("Ошибка перекодировки аудио", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "models.sample_failed")})
+	}
+
+	return c.Send(&telebot.Voice{
+		File:     telebot.File{FileReader: bytes.NewReader(ogg)},
+		MIME:     "audio/ogg",
+		Duration: dur,
+		Caption:  h.t(c, "models.sample_caption", model.Name),
+	})
+}
+
+func (h *Handler) OnModelRename(c telebot.Context, p callback.Payload) error {
+	return h.startModelEdit(c, p, defs.WaitingRenameModelName, "models.ask_rename")
+}
+
+func (h *Handler) OnModelSample(c telebot.Context, p callback.Payload) error {
+	return h.startModelEdit(c, p, defs.WaitingSample, "models.ask_sample")
+}
+
+func (h *Handler) OnModelDelete(c telebot.Context, p callback.Payload) error {
+	model, page, err := h.callbackModel(c, p)
+	if err != nil {
+		return h.respondModelError(c, err, page)
+	}
+
+	kb := h.callbacks.Keyboard(c.Sender().ID)
+	markup := &telebot.ReplyMarkup{}
+	markup.Inline(markup.Row(
+		modelBtn(kb, h.t(c, "models.btn_delete_yes"), ActionModelDeleteConfirm, model.ID, page),
+		modelBtn(kb, h.t(c, "models.btn_cancel"), ActionModelOpen, model.ID, page),
+	))
+	if err := kb.Err(); err != nil {
+		return err
+	}
+
+	return c.Edit(h.t(c, "models.delete_confirm", model.Name), markup)
+}
+
+func (h *Handler) OnModelDeleteConfirm(c telebot.Context, p callback.Payload) error {
+	ctx := requestContext(c)
+	userID := c.Sender().ID
+	model, page, err := h.callbackModel(c, p)
+	if err != nil {
+		return h.respondModelError(c, err, page)
+	}
+
+	err = h.service.DeleteModel(ctx, userID, model.ID)
+	if err != nil {
+		h.log.Error("Ошибка удаления модели", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "models.delete_failed")})
+	}
+	h.log.Info("Модель успешно удалена", zap.String("modelName", model.Name))
+
+	text, markup, err := h.renderModelList(c, page)
+	if err != nil {
+		h.log.Error("Ошибка получения списка моделей", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "models.load_failed")})
+	}
+
+	notice := h.t(c, "models.deleted")
+	if active, err := h.service.GetActiveModel(ctx, userID); err == nil {
+		notice = h.t(c, "models.deleted_active", active.Name)
+	}
+
+	_ = c.Respond(&telebot.CallbackResponse{Text: notice})
+	return c.Edit(text, markup)
+}
+
+func (h *Handler) startModelEdit(c telebot.Context, p callback.Payload, state string, promptKey string) error {
+	scope := dialogScope(c)
+	model, page, err := h.callbackModel(c, p)
+	if err != nil {
+		return h.respondModelError(c, err, page)
+	}
+
+	err = h.service.SetEditingModel(scope, model.ID)
+	if err != nil {
+		h.log.Error("Ошибка установки редактируемой модели", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "error.generic")})
+	}
+
+	err = h.service.SetUserState(scope, state)
+	if err != nil {
+		h.log.Error("Ошибка обновления состояния пользователя", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "error.generic")})
+	}
+
+	return c.Send(h.t(c, promptKey, model.Name))
+}
+
+// callbackModel загружает модель из данных кнопки, проверяя, что она
+// принадлежит пользователю. Номер страницы списка передаётся в Arg.
+func (h *Handler) callbackModel(c telebot.Context, p callback.Payload) (defs.Model, int, error) {
+	ctx := requestContext(c)
+	page := int(p.Arg)
+	model, err := h.service.GetModel(ctx, c.Sender().ID, p.ModelID)
+	if err != nil {
+		return defs.Model{}, page, err
+	}
+	return model, page, nil
+}
+
+// respondModelError отвечает на callback и, если модель уже удалена,
+// перерисовывает список, чтобы убрать устаревшие кнопки.
+func (h *Handler) respondModelError(c telebot.Context, err error, page int) error {
+	if !errors.Is(err, defs.ErrNoModel{}) {
+		h.log.Error("Ошибка обработки кнопки модели", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "error.generic")})
+	}
+
+	_ = c.Respond(&telebot.CallbackResponse{Text: h.t(c, "model.not_found")})
+	text, markup, err := h.renderModelList(c, page)
+	if err != nil {
+		h.log.Error("Ошибка получения списка моделей", zap.Error(err))
+		return nil
+	}
+	return c.Edit(text, markup)
+}
+
+func (h *Handler) renderModelList(c telebot.Context, page int) (string, *telebot.ReplyMarkup, error) {
+	ctx := requestContext(c)
+	userID := c.Sender().ID
+	models, err := h.service.GetUserModels(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var activeID int64
+	if active, err := h.service.GetActiveModel(ctx, userID); err == nil {
+		activeID = active.ID
+	}
+
+	markup := &telebot.ReplyMarkup{}
+	if len(models) == 0 {
+		markup.Inline()
+		return h.t(c, "models.empty"), markup, nil
+	}
+
+	pages := (len(models) + defs.ModelsPerPage - 1) / defs.ModelsPerPage
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	start := page * defs.ModelsPerPage
+	end := min(start+defs.ModelsPerPage, len(models))
+	kb := h.callbacks.Keyboard(userID)
+
+	rows := make([]telebot.Row, 0, end-start+1)
+	for _, model := range models[start:end] {
+		label := model.Name
+		if model.ID == activeID {
+			label = "✅ " + label
+		}
+		rows = append(rows, markup.Row(modelBtn(kb, label, ActionModelOpen, model.ID, page)))
+	}
+
+	if pages > 1 {
+		var nav telebot.Row
+		if page > 0 {
+			nav = append(nav, pageBtn(kb, "◀️", page-1))
+		}
+		nav = append(nav, pageBtn(kb, fmt.Sprintf("%d/%d", page+1, pages), page))
+		if page < pages-1 {
+			nav = append(nav, pageBtn(kb, "▶️", page+1))
+		}
+		rows = append(rows, nav)
+	}
+	if err := kb.Err(); err != nil {
+		return "", nil, err
+	}
+	markup.Inline(rows...)
+
+	return h.n(c, "models.title", len(models), len(models), defs.MaxModels), markup, nil
+}
+
+func (h *Handler) isActiveModel(ctx context.Context, userID int64, modelID int64) bool {
+	active, err := h.service.GetActiveModel(ctx, userID)
+	return err == nil && active.ID == modelID
+}
+
+func (h *Handler) renderModelCard(c telebot.Context, model defs.Model, page int, active bool) (string, *telebot.ReplyMarkup, error) {
+	kb := h.callbacks.Keyboard(c.Sender().ID)
+	markup := &telebot.ReplyMarkup{}
+
+	text := h.t(c, "models.card", model.Name)
+	selectLabel := h.t(c, "models.btn_select")
+	if active {
+		text = h.t(c, "models.card_active", model.Name)
+		selectLabel = h.t(c, "models.btn_selected")
+	}
+
+	markup.Inline(
+		markup.Row(
+			modelBtn(kb, selectLabel, ActionModelSelect, model.ID, page),
+			modelBtn(kb, h.t(c, "models.btn_preview"), ActionModelPreview, model.ID, page),
+		),
+		markup.Row(
+			modelBtn(kb, h.t(c, "models.btn_rename"), ActionModelRename, model.ID, page),
+			modelBtn(kb, h.t(c, "models.btn_sample"), ActionModelSample, model.ID, page),
+		),
+		markup.Row(
+			modelBtn(kb, h.t(c, "models.btn_delete"), ActionModelDelete, model.ID, page),
+			pageBtn(kb, h.t(c, "models.btn_back"), page),
+		),
+	)
+	if err := kb.Err(); err != nil {
+		return "", nil, err
+	}
+
+	return text, markup, nil
+}
+
+func modelBtn(kb *callback.Keyboard, text string, action callback.Action, modelID int64, page int) telebot.Btn {
+	return kb.Btn(text, callback.Payload{Action: action, ModelID: modelID, Arg: int64(page)})
+}
+
+func pageBtn(kb *callback.Keyboard, text string, page int) telebot.Btn {
+	return kb.Btn(text, callback.Payload{Action: ActionModelsPage, Arg: int64(page)})
+}
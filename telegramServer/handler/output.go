@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"gopkg.in/telebot.v3"
+	"kursach/audio"
+	"kursach/callback"
+	"kursach/defs"
+	"kursach/tracing"
+	"strings"
+)
+
+// maxSpeechTitle — длина названия аудиофайла, которое берётся из начала
+// озвученного текста.
+const maxSpeechTitle = 64
+
+// ChooseFormat показывает выбор формата, в котором бот присылает
+// озвученный текст.
+func (h *Handler) ChooseFormat(c telebot.Context) error {
+	markup, err := h.formatMarkup(c)
+	if err != nil {
+		h.log.Error("Ошибка получения формата ответа", zap.Error(err))
+		return c.Send(h.t(c, "error.generic"))
+	}
+	return c.Send(h.t(c, "format.choose"), markup)
+}
+
+// OnOutputFormat сохраняет формат ответа. Arg — номер формата в
+// defs.OutputFormats, начиная с 1.
+func (h *Handler) OnOutputFormat(c telebot.Context, p callback.Payload) error {
+	ctx := requestContext(c)
+	if p.Arg < 1 || int(p.Arg) > len(defs.OutputFormats) {
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "callback.forged")})
+	}
+	format := defs.OutputFormats[p.Arg-1]
+
+	err := h.service.SetOutputFormat(ctx, c.Sender().ID, format)
+	if err != nil {
+		h.log.Error("Ошибка сохранения формата ответа", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "error.generic")})
+	}
+
+	markup, err := h.formatMarkup(c)
+	if err != nil {
+		h.log.Error("Ошибка получения формата ответа", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "error.generic")})
+	}
+	return c.Edit(h.t(c, "format.set", h.t(c, "format."+format)), markup)
+}
+
+func (h *Handler) formatMarkup(c telebot.Context) (*telebot.ReplyMarkup, error) {
+	ctx := requestContext(c)
+	userID := c.Sender().ID
+	current, err := h.service.GetOutputFormat(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	kb := h.callbacks.Keyboard(userID)
+	markup := &telebot.ReplyMarkup{}
+	rows := make([]telebot.Row, 0, len(defs.OutputFormats))
+	for i, format := range defs.OutputFormats {
+		text := h.t(c, "format."+format)
+		if format == current {
+			text = "✅ " + text
+		}
+		rows = append(rows, markup.Row(kb.Btn(text, callback.Payload{Action: ActionOutputFormat, Arg: int64(i + 1)})))
+	}
+	if err := kb.Err(); err != nil {
+		return nil, err
+	}
+	markup.Inline(rows...)
+	return markup, nil
+}
+
+// sendSpeech отправляет озвучку text моделью model в формате, выбранном
+// пользователем. Если озвучка уже отправлялась в этом формате, Telegram
+// получает file_id вместо повторной загрузки файла.
+func (h *Handler) sendSpeech(c telebot.Context, model defs.Model, text string, speech defs.Speech) error {
+	ctx := requestContext(c)
+	format, err := h.service.GetOutputFormat(ctx, c.Sender().ID)
+	if err != nil {
+		return err
+	}
+	return h.sendSpeechAs(c, format, model, text, speech)
+}
+
+// sendSpeechAs отправляет озвучку в формате format, сохраняет её в
+// историю пользователя и добавляет кнопки вариантов.
+func (h *Handler) sendSpeechAs(c telebot.Context, format string, model defs.Model, text string, speech defs.Speech) error {
+	ctx := requestContext(c)
+	if format == defs.OutputVideoNote && speech.Duration > defs.MaxVideoNoteDuration {
+		format = defs.OutputVoice
+	}
+
+	var what telebot.Sendable
+	fileID, cached := h.service.CachedFileID(speech.Key, format)
+	if cached {
+		what = speechFile(format, telebot.File{FileID: fileID}, model, text, speech.Duration)
+	} else {
+		data, err := encodeSpeech(ctx, format, model, text, speech)
+		if err != nil {
+			return err
+		}
+		what = speechFile(format, telebot.FromReader(bytes.NewReader(data)), model, text, speech.Duration)
+	}
+
+	opts := &telebot.SendOptions{}
+	if isGroup(c) {
+		opts.ReplyTo = c.Message()
+	}
+	// Загрузка видеосообщения или длинного MP3 занимает заметное время.
+	_, span := tracer.Start(ctx, "telegram.send", trace.WithAttributes(
+		attribute.String("speech.format", format),
+		attribute.Bool("speech.cached", cached),
+	))
+	msg, err := c.Bot().Send(c.Recipient(), what, opts)
+	tracing.End(span, err)
+	if err != nil {
+		return err
+	}
+
+	fileID = sentFileID(msg)
+	if fileID == "" {
+		return nil
+	}
+	h.service.CacheFileID(speech.Key, format, fileID)
+
+	generationID, err := h.service.RecordGeneration(ctx, defs.Generation{
+		UserID:   c.Sender().ID,
+		Model:    model,
+		Text:     text,
+		Params:   defs.GenerationParams{Format: format, Speed: speech.Speed},
+		Duration: speech.Duration,
+		FileID:   fileID,
+	})
+	if err != nil {
+		h.log.Error("Ошибка сохранения озвучки, кнопки не добавлены", zap.Error(err))
+		return nil
+	}
+
+	// Кнопки ссылаются на запись озвучки, поэтому добавляются после
+	// отправки.
+	markup, err := h.speechMarkup(c, generationID)
+	if err == nil {
+		_, err = c.Bot().EditReplyMarkup(msg, markup)
+	}
+	if err != nil {
+		h.log.Warn("Ошибка добавления кнопок к озвучке", zap.Error(err))
+	}
+	return nil
+}
+
+// encodeSpeech перекодирует голосовое сообщение в формат format.
+func encodeSpeech(ctx context.Context, format string, model defs.Model, text string, speech defs.Speech) ([]byte, error) {
+	tags := audio.Tags{Title: speechTitle(text), Artist: model.Name}
+
+	var (
+		data []byte
+		err  error
+	)
+	switch format {
+	case defs.OutputMP3:
+		data, _, err = audio.ToMP3(ctx, speech.Opus, tags)
+	case defs.OutputM4A:
+		data, _, err = audio.ToM4A(ctx, speech.Opus, tags)
+	case defs.OutputWAV:
+		data, _, err = audio.ToWAV(ctx, speech.Opus)
+	case defs.OutputVideoNote:
+		data, _, err = audio.ToVideoNote(ctx, speech.Opus)
+	default:
+		data = speech.Opus
+	}
+	return data, err
+}
+
+// speechFile собирает сообщение формата format с файлом file.
+func speechFile(format string, file telebot.File, model defs.Model, text string, duration int) telebot.Sendable {
+	title := speechTitle(text)
+
+	switch format {
+	case defs.OutputMP3:
+		return &telebot.Audio{
+			File:      file,
+			Duration:  duration,
+			Title:     title,
+			Performer: model.Name,
+			MIME:      "audio/mpeg",
+			FileName:  safeFileName(title) + ".mp3",
+		}
+	case defs.OutputM4A:
+		return &telebot.Audio{
+			File:      file,
+			Duration:  duration,
+			Title:     title,
+			Performer: model.Name,
+			MIME:      "audio/mp4",
+			FileName:  safeFileName(title) + ".m4a",
+		}
+	case defs.OutputWAV:
+		return &telebot.Document{
+			File:     file,
+			MIME:     "audio/wav",
+			FileName: safeFileName(title) + ".wav",
+		}
+	case defs.OutputVideoNote:
+		return &telebot.VideoNote{
+			File:     file,
+			Duration: duration,
+			Length:   audio.VideoNoteSize,
+		}
+	}
+	return &telebot.Voice{
+		File:     file,
+		MIME:     "audio/ogg",
+		Duration: duration,
+	}
+}
+
+// sentFileID возвращает file_id файла из отправленного сообщения.
+func sentFileID(msg *telebot.Message) string {
+	switch {
+	case msg.Voice != nil:
+		return msg.Voice.FileID
+	case msg.Audio != nil:
+		return msg.Audio.FileID
+	case msg.Document != nil:
+		return msg.Document.FileID
+	case msg.VideoNote != nil:
+		return msg.VideoNote.FileID
+	}
+	return ""
+}
+
+// speechTitle — начало озвученного текста, название для аудиофайла.
+func speechTitle(text string) string {
+	title := []rune(strings.Join(strings.Fields(text), " "))
+	if len(title) > maxSpeechTitle {
+		return string(title[:maxSpeechTitle-1]) + "…"
+	}
+	return string(title)
+}
+
+// safeFileName заменяет символы, недопустимые в именах файлов, и
+// обрезает слишком длинные имена.
+func safeFileName(name string) string {
+	runes := []rune(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name))
+	if len(runes) > 60 {
+		runes = runes[:60]
+	}
+	return string(runes)
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"gopkg.in/telebot.v3"
+	"slices"
+	"strings"
+	"unicode/utf16"
+)
+
+// speechText извлекает из сообщения текст для озвучки: текст или
+// подпись к медиа. Ссылки, почта, команды и блоки кода вырезаются,
+// у упоминаний и хэштегов убираются служебные символы.
+func speechText(msg *telebot.Message) string {
+	text, entities := msg.Text, msg.Entities
+	if text == "" {
+		text, entities = msg.Caption, msg.CaptionEntities
+	}
+	return cleanSpeechText(text, entities)
+}
+
+func cleanSpeechText(text string, entities telebot.Entities) string {
+	units := utf16.Encode([]rune(text))
+
+	sorted := slices.Clone(entities)
+	slices.SortStableFunc(sorted, func(a, b telebot.MessageEntity) int {
+		return a.Offset - b.Offset
+	})
+
+	out := make([]uint16, 0, len(units))
+	pos := 0
+	for _, entity := range sorted {
+		// Вложенные и пересекающиеся entity пропускаются: внешняя уже
+		// обработана целиком.
+		if entity.Offset < pos || entity.Offset >= len(units) {
+			continue
+		}
+		end := min(entity.Offset+entity.Length, len(units))
+
+		var replacement []uint16
+		switch entity.Type {
+		case telebot.EntityURL, telebot.EntityEmail, telebot.EntityCommand, telebot.EntityCodeBlock:
+		case telebot.EntityMention, telebot.EntityHashtag:
+			replacement = units[min(entity.Offset+1, end):end]
+		default:
+			continue
+		}
+
+		out = append(out, units[pos:entity.Offset]...)
+		out = append(out, replacement...)
+		pos = end
+	}
+	out = append(out, units[pos:]...)
+
+	return strings.Join(strings.Fields(string(utf16.Decode(out))), " ")
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"testing"
+
+	"gopkg.in/telebot.v3"
+)
+
+func TestCleanSpeechText(t *testing.T) {
+	entity := func(typ telebot.EntityType, offset, length int) telebot.MessageEntity {
+		return telebot.MessageEntity{Type: typ, Offset: offset, Length: length}
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		entities telebot.Entities
+		want     string
+	}{
+		{
+			name: "без entity",
+			text: "  привет,\n  мир ",
+			want: "привет, мир",
+		},
+		{
+			name:     "ссылка и почта вырезаются",
+			text:     "см. https://example.com или a@b.ru",
+			entities: telebot.Entities{entity(telebot.EntityURL, 4, 19), entity(telebot.EntityEmail, 28, 6)},
+			want:     "см. или",
+		},
+		{
+			name:     "у упоминания и хэштега убирается символ",
+			text:     "@user #новости",
+			entities: telebot.Entities{entity(telebot.EntityMention, 0, 5), entity(telebot.EntityHashtag, 6, 8)},
+			want:     "user новости",
+		},
+		{
+			// 😀 и 𝔸 занимают по две UTF-16 code units.
+			name:     "смещения после эмодзи",
+			text:     "😀𝔸 @user /start текст",
+			entities: telebot.Entities{entity(telebot.EntityMention, 5, 5), entity(telebot.EntityCommand, 11, 6)},
+			want:     "😀𝔸 user текст",
+		},
+		{
+			name:     "entity не по порядку",
+			text:     "/say привет https://example.com",
+			entities: telebot.Entities{entity(telebot.EntityURL, 12, 19), entity(telebot.EntityCommand, 0, 4)},
+			want:     "привет",
+		},
+		{
+			name:     "вложенная entity пропускается",
+			text:     "```код @user``` текст",
+			entities: telebot.Entities{entity(telebot.EntityCodeBlock, 0, 15), entity(telebot.EntityMention, 7, 5)},
+			want:     "текст",
+		},
+		{
+			name:     "форматирование сохраняется",
+			text:     "важный текст",
+			entities: telebot.Entities{entity(telebot.EntityBold, 0, 6)},
+			want:     "важный текст",
+		},
+		{
+			name:     "entity за концом текста",
+			text:     "привет @user",
+			entities: telebot.Entities{entity(telebot.EntityMention, 7, 20), entity(telebot.EntityURL, 40, 5)},
+			want:     "привет user",
+		},
+		{
+			name:     "пустое упоминание",
+			text:     "привет",
+			entities: telebot.Entities{entity(telebot.EntityMention, 2, 0)},
+			want:     "привет",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanSpeechText(tt.text, tt.entities); got != tt.want {
+				t.Errorf("cleanSpeechText(%q) = %q, ожидалось %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/telebot.v3"
+	"kursach/tracing"
+)
+
+// updateContextKey — ключ контекста обновления в telebot.Context.
+const updateContextKey = "trace.ctx"
+
+var tracer = otel.Tracer("kursach/handler")
+
+// TraceUpdates начинает спан на каждое обновление. Обработчики получают
+// контекст со спаном через requestContext, поэтому запросы к базе,
+// ffmpeg и AudioProcessor попадают в трейс обновления.
+func TraceUpdates(next telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(c telebot.Context) (err error) {
+		update := c.Update()
+		kind := updateType(update)
+
+		attrs := []attribute.KeyValue{
+			attribute.Int("telegram.update_id", update.ID),
+			attribute.String("telegram.update_type", kind),
+		}
+		if command := updateCommand(update); command != "" {
+			attrs = append(attrs, attribute.String("telegram.command", command))
+		}
+		if sender := c.Sender(); sender != nil {
+			attrs = append(attrs, attribute.Int64("telegram.user_id", sender.ID))
+		}
+		if chat := c.Chat(); chat != nil {
+			attrs = append(attrs, attribute.Int64("telegram.chat_id", chat.ID))
+		}
+
+		ctx, span := tracer.Start(context.Background(), "telegram.update "+kind,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+		)
+		defer func() { tracing.End(span, err) }()
+
+		c.Set(updateContextKey, ctx)
+		return next(c)
+	}
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"go.uber.org/zap"
+	"gopkg.in/telebot.v3"
+	"kursach/book"
+	"kursach/defs"
+	"regexp"
+	"strings"
+)
+
+var languageHint = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+// Transcribe отвечает текстом на голосовое, аудио, видео или
+// видеосообщение, на которое пользователь ответил командой
+// "/transcribe [язык]". Язык — подсказка для распознавания, например,
+// "en"; без неё язык определяется автоматически.
+func (h *Handler) Transcribe(c telebot.Context) error {
+	msg := c.Message()
+	h.log.Info("Transcribe called", zap.Int64("userID", c.Sender().ID), zap.Int64("chatID", c.Chat().ID))
+
+	language := ""
+	if args := c.Args(); len(args) > 0 {
+		var ok bool
+		language, ok = parseLanguageHint(args[0])
+		if !ok {
+			return c.Reply(h.t(c, "transcribe.bad_language", args[0]))
+		}
+	}
+
+	if msg.ReplyTo == nil {
+		return c.Reply(h.t(c, "transcribe.usage"))
+	}
+	file := mediaFile(msg.ReplyTo)
+	if file == nil {
+		return c.Reply(h.t(c, "transcribe.no_media"))
+	}
+	if file.FileSize > defs.MaxDownloadSize {
+		return c.Reply(h.t(c, "file.too_large", defs.MaxDownloadSize>>20))
+	}
+
+	_ = c.Notify(telebot.Typing)
+
+	data, err := h.downloadFile(c, file)
+	if err != nil {
+		h.log.Error("Ошибка загрузки файла", zap.Error(err))
+		return c.Reply(h.t(c, "error.generic"))
+	}
+
+	text, err := h.service.Transcribe(requestContext(c), c.Sender().ID, data, language)
+	if err != nil {
+		h.log.Error("Ошибка распознавания речи", zap.Error(err))
+		return c.Reply(h.failureText(c, err))
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return c.Reply(h.t(c, "voice.no_speech"))
+	}
+
+	for _, chunk := range book.Segments(text, defs.MessageChunkLen) {
+		if err := c.Reply(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mediaFile возвращает файл со звуком из сообщения или nil, если
+// распознавать нечего.
+func mediaFile(msg *telebot.Message) *telebot.File {
+	switch {
+	case msg.Voice != nil:
+		return &msg.Voice.File
+	case msg.Audio != nil:
+		return &msg.Audio.File
+	case msg.VideoNote != nil:
+		return &msg.VideoNote.File
+	case msg.Video != nil:
+		return &msg.Video.File
+	case msg.Document != nil && (strings.HasPrefix(msg.Document.MIME, "audio/") || strings.HasPrefix(msg.Document.MIME, "video/")):
+		return &msg.Document.File
+	}
+	return nil
+}
+
+// parseLanguageHint приводит подсказку языка к коду ISO 639, например,
+// "EN" к "en". Если аргумент не похож на код языка, ok равен false.
+func parseLanguageHint(arg string) (language string, ok bool) {
+	language = strings.ToLower(arg)
+	return language, languageHint.MatchString(language)
+}
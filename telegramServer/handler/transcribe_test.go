@@ -0,0 +1,26 @@
+package handler
+
+import "testing"
+
+func TestParseLanguageHint(t *testing.T) {
+	tests := []struct {
+		arg      string
+		language string
+		ok       bool
+	}{
+		{"en", "en", true},
+		{"RU", "ru", true},
+		{"Ukr", "ukr", true},
+		{"english", "english", false},
+		{"e", "e", false},
+		{"en-US", "en-us", false},
+		{"e1", "e1", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		language, ok := parseLanguageHint(tt.arg)
+		if language != tt.language || ok != tt.ok {
+			t.Errorf("parseLanguageHint(%q) = %q, %v, want %q, %v", tt.arg, language, ok, tt.language, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"errors"
+	"go.uber.org/zap"
+	"gopkg.in/telebot.v3"
+	"kursach/callback"
+	"kursach/defs"
+)
+
+// speechMarkup — кнопки под озвучкой. Они повторяют запрос из записи
+// истории generationID с изменёнными параметрами.
+func (h *Handler) speechMarkup(c telebot.Context, generationID int64) (*telebot.ReplyMarkup, error) {
+	kb := h.callbacks.Keyboard(c.Sender().ID)
+	markup := &telebot.ReplyMarkup{}
+	markup.Inline(
+		markup.Row(
+			speechBtn(kb, h.t(c, "speech.btn_regenerate"), ActionHistoryRegenerate, 0, generationID),
+			speechBtn(kb, h.t(c, "speech.btn_model"), ActionSpeechModels, 0, generationID),
+		),
+		markup.Row(
+			speechBtn(kb, h.t(c, "speech.btn_slower"), ActionSpeechSlower, 0, generationID),
+			speechBtn(kb, h.t(c, "speech.btn_faster"), ActionSpeechFaster, 0, generationID),
+		),
+	)
+	if err := kb.Err(); err != nil {
+		return nil, err
+	}
+	return markup, nil
+}
+
+func (h *Handler) OnSpeechSlower(c telebot.Context, p callback.Payload) error {
+	return h.changeSpeed(c, p, -defs.SpeedStep)
+}
+
+func (h *Handler) OnSpeechFaster(c telebot.Context, p callback.Payload) error {
+	return h.changeSpeed(c, p, defs.SpeedStep)
+}
+
+// OnSpeechModels заменяет кнопки под озвучкой списком других моделей
+// пользователя. Arg — ID записи истории.
+func (h *Handler) OnSpeechModels(c telebot.Context, p callback.Payload) error {
+	ctx := requestContext(c)
+	userID := c.Sender().ID
+	g, err := h.service.GetGeneration(ctx, userID, p.Arg)
+	if err != nil {
+		return h.respondGenerationError(c, err)
+	}
+
+	models, err := h.service.GetUserModels(ctx, userID)
+	if err != nil {
+		h.log.Error("Ошибка получения списка моделей", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "models.load_failed")})
+	}
+
+	kb := h.callbacks.Keyboard(userID)
+	markup := &telebot.ReplyMarkup{}
+	rows := make([]telebot.Row, 0, len(models)+1)
+	for _, model := range models {
+		if model.ID == g.Model.ID {
+			continue
+		}
+		rows = append(rows, markup.Row(speechBtn(kb, model.Name, ActionSpeechModel, model.ID, g.ID)))
+	}
+	if len(rows) == 0 {
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "speech.no_other_models")})
+	}
+	rows = append(rows, markup.Row(speechBtn(kb, h.t(c, "models.btn_back"), ActionSpeechBack, 0, g.ID)))
+	if err := kb.Err(); err != nil {
+		return err
+	}
+	markup.Inline(rows...)
+
+	_, err = c.Bot().EditReplyMarkup(c.Message(), markup)
+	return err
+}
+
+// OnSpeechModel озвучивает текст записи моделью из ModelID с теми же
+// параметрами. Arg — ID записи истории.
+func (h *Handler) OnSpeechModel(c telebot.Context, p callback.Payload) error {
+	ctx := requestContext(c)
+	userID := c.Sender().ID
+	g, err := h.service.GetGeneration(ctx, userID, p.Arg)
+	if err != nil {
+		return h.respondGenerationError(c, err)
+	}
+
+	model, err := h.service.GetModel(ctx, userID, p.ModelID)
+	if err != nil {
+		if errors.Is(err, defs.ErrNoModel{}) {
+			return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "model.not_found")})
+		}
+		h.log.Error("Ошибка получения модели", zap.Error(err))
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "error.generic")})
+	}
+
+	h.restoreSpeechMarkup(c, g.ID)
+	return h.revoiceGeneration(c, g, model, g.Params.Speed, false)
+}
+
+// OnSpeechBack возвращает под озвучкой основные кнопки.
+func (h *Handler) OnSpeechBack(c telebot.Context, p callback.Payload) error {
+	h.restoreSpeechMarkup(c, p.Arg)
+	return nil
+}
+
+func (h *Handler) changeSpeed(c telebot.Context, p callback.Payload, delta float64) error {
+	ctx := requestContext(c)
+	g, err := h.service.GetGeneration(ctx, c.Sender().ID, p.Arg)
+	if err != nil {
+		return h.respondGenerationError(c, err)
+	}
+
+	speed := g.Params.Speed + delta
+	if speed < defs.MinSpeed || speed > defs.MaxSpeed {
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "speech.speed_limit")})
+	}
+
+	model, err := h.generationModel(c, g)
+	if err != nil {
+		return h.respondGenerationModelError(c, g, err)
+	}
+	return h.revoiceGeneration(c, g, model, speed, false)
+}
+
+// revoiceGeneration озвучивает текст записи g моделью model в темпе
+// speed и присылает результат новым сообщением в формате записи. fresh
+// означает новый дубль в обход кэша синтеза.
+func (h *Handler) revoiceGeneration(c telebot.Context, g defs.Generation, model defs.Model, speed float64, fresh bool) error {
+	userID := c.Sender().ID
+
+	// Синтез занимает время, поэтому на нажатие отвечаем сразу.
+	_ = c.Respond(&telebot.CallbackResponse{Text: h.t(c, "speech.regenerating")})
+
+	h.log.Info("Повторная озвучка записи истории",
+		zap.Int64("userID", userID),
+		zap.Int64("generationID", g.ID),
+		zap.Int64("modelID", model.ID),
+		zap.Float64("speed", speed),
+	)
+
+	var (
+		speech defs.Speech
+		err    error
+	)
+	if fresh {
+		speech, err = h.service.Regenerate(requestContext(c), userID, model, g.Text, speed)
+	} else {
+		speech, err = h.service.Synthesize(requestContext(c), userID, model, g.Text, speed)
+	}
+	if err != nil {
+		h.log.Error("Ошибка генерации аудио", zap.Error(err))
+		return c.Send(h.failureText(c, err))
+	}
+
+	if err := h.sendSpeechAs(c, g.Params.Format, model, g.Text, speech); err != nil {
+		h.log.Error("Ошибка отправки озвучки", zap.Error(err))
+		return c.Send(h.t(c, "error.generic"))
+	}
+	return nil
+}
+
+// generationModel возвращает модель записи: модель пользователя или
+// общую модель чата, в котором была озвучка.
+func (h *Handler) generationModel(c telebot.Context, g defs.Generation) (defs.Model, error) {
+	ctx := requestContext(c)
+	if model, err := h.service.ResolveModel(ctx, dialogScope(c)); err == nil && model.ID == g.Model.ID {
+		return model, nil
+	}
+	return h.service.GetModel(ctx, c.Sender().ID, g.Model.ID)
+}
+
+func (h *Handler) respondGenerationModelError(c telebot.Context, g defs.Generation, err error) error {
+	if errors.Is(err, defs.ErrNoModel{}) {
+		return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "history.model_unavailable", g.Model.Name), ShowAlert: true})
+	}
+	h.log.Error("Ошибка получения модели", zap.Error(err))
+	return c.Respond(&telebot.CallbackResponse{Text: h.t(c, "error.generic")})
+}
+
+func (h *Handler) restoreSpeechMarkup(c telebot.Context, generationID int64) {
+	markup, err := h.speechMarkup(c, generationID)
+	if err == nil {
+		_, err = c.Bot().EditReplyMarkup(c.Message(), markup)
+	}
+	if err != nil && !errors.Is(err, telebot.ErrSameMessageContent) {
+		h.log.Warn("Ошибка обновления кнопок озвучки", zap.Error(err))
+	}
+}
+
+func speechBtn(kb *callback.Keyboard, text string, action callback.Action, modelID int64, generationID int64) telebot.Btn {
+	return kb.Btn(text, callback.Payload{Action: action, ModelID: modelID, Arg: generationID})
+}
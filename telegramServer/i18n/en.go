@@ -0,0 +1,181 @@
+package i18n
+
+var enMessages = map[string]string{
+	"command.save_model":    "Create a model from a voice, audio or video.",
+	"command.models":        "Manage models: select, preview, delete.",
+	"command.current":       "Show the current model.",
+	"command.format":        "Choose the reply format: voice, MP3, WAV, video.",
+	"command.history":       "Voicing history: resend or regenerate.",
+	"command.language":      "Choose the interface language.",
+	"command.start":         "Start",
+	"command.say":           "Voice text: /say text",
+	"command.transcribe":    "Speech to text: reply to a voice with /transcribe",
+	"command.chat_settings": "Bot settings for this chat.",
+
+	"start.text": `Hi! 👋
+
+I help you create voice models and generate audio.
+
+Here is what you can do:
+
+/save_model — create a new voice model
+/models — select, preview, rename or delete models
+/current — show the current model
+/format — choose how to receive voiced text
+/history — voicing history
+/language — change the language
+/say — voice text, in groups too
+/transcribe — turn speech from a voice, audio or video into text
+/start — show these instructions again
+
+⚡ Just send me some text and I will voice it with the selected model!
+🎙 Send a voice message and I will repeat it in the voice of the selected model.
+💬 In any chat type @bot_name and some text to send a voiced message.
+👥 In a group mention me, reply to my message or use /say. An administrator can configure me with /chat_settings.
+📚 Send a book in .txt, .md, .fb2 or .epub and I will voice it chapter by chapter.`,
+
+	"error.generic":   "Something went wrong, please try again later.",
+	"error.busy":      "The voice service is restarting or overloaded. Please try again in a minute.",
+	"command.unknown": "Unknown command.",
+
+	"callback.forged": "Invalid button.",
+	"callback.stale":  "This button has expired, please open the menu again.",
+
+	"model.ask_name":        "Enter a model name:",
+	"model.name_empty":      "The model name cannot be empty.",
+	"model.name_invalid":    "The model name cannot contain \"/\", \"\\\", \"..\" or control characters.",
+	"model.name_taken":      "You already have a model with this name.",
+	"model.send_voice":      "Send a voice message, an audio file, a video or a video note with the voice to create the model.",
+	"model.not_found":       "Model not found.",
+	"model.pending_missing": "Model name not found.",
+	"model.saved":           "Model \"%s\" is saved and selected for generation.",
+	"model.rename_failed":   "Failed to rename the model.",
+	"model.renamed":         "The model is renamed to \"%s\".",
+	"model.required":        "Create a model with /save_model or pick a saved one with /models.",
+	"model.none_active":     "No model is selected. Create one with /save_model.",
+	"model.current":         "Current model: \"%s\". You can change it with /models.",
+	"voice.no_speech":       "I could not make out any speech in the voice message.",
+	"voice.recognized":      "📝 Recognized text:\n<code>%s</code>\n\nIf something is wrong, send me the corrected text and I will voice it again.",
+	"sample.added":          "The sample is added to the model.",
+
+	"models.load_failed":    "Failed to load models.",
+	"models.empty":          "You have no saved models yet. Create one with /save_model.",
+	"models.selected":       "Selected model: %s",
+	"models.sample_failed":  "Failed to load the sample.",
+	"models.sample_caption": "Sample of model \"%s\"",
+	"models.ask_rename":     "Enter a new name for model \"%s\":",
+	"models.ask_sample":     "Send a voice message, an audio file, a video or a video note, it will be added to the sample of model \"%s\".",
+	"models.delete_confirm": "Delete model \"%s\"? This cannot be undone.",
+	"models.delete_failed":  "Failed to delete the model.",
+	"models.deleted":        "The model is deleted.",
+	"models.deleted_active": "The model is deleted. Current model: %s",
+	"models.card":           "Model \"%s\"",
+	"models.card_active":    "✅ Model \"%s\" (current)",
+	"models.btn_select":     "Select",
+	"models.btn_selected":   "✅ Selected",
+	"models.btn_preview":    "🔊 Preview",
+	"models.btn_rename":     "✏️ Rename",
+	"models.btn_sample":     "➕ Add sample",
+	"models.btn_delete":     "🗑 Delete",
+	"models.btn_delete_yes": "🗑 Yes, delete",
+	"models.btn_cancel":     "Cancel",
+	"models.btn_back":       "⬅️ Back",
+
+	"inline.hint":     "Type some text and I will voice it with your current model",
+	"inline.disabled": "Inline mode is not configured",
+	"inline.no_model": "Create a model in the chat with the bot first",
+	"inline.failed":   "Failed to voice the text, please try again",
+	"inline.busy":     "The voice service is busy, try again in a minute",
+
+	"say.empty":             "Write the text after the command (/say text) or reply to a message with /say.",
+	"say.no_text":           "This message has no text to voice.",
+	"chat.group_only":       "This command works only in groups.",
+	"chat.not_admin":        "Only a chat administrator can do this.",
+	"chat.admin_only":       "Only administrators can voice text in this chat.",
+	"chat.settings":         "Chat settings\n\nWho can voice text: %s\nModel: %s",
+	"chat.access_all":       "all members",
+	"chat.access_admins":    "administrators only",
+	"chat.model_own":        "everyone uses their current model",
+	"chat.model_shared":     "shared model \"%s\"",
+	"chat.btn_admin_only":   "🔒 Administrators only",
+	"chat.btn_everyone":     "🔓 All members",
+	"chat.btn_use_my_model": "🎙 Share my current model",
+	"chat.btn_own_models":   "👥 Everyone uses their own model",
+
+	"book.unsupported":  "I can voice books in .txt, .md, .fb2 and .epub formats.",
+	"file.too_large":    "The file is too large: a bot can download at most %d MB.",
+	"book.parse_failed": "Failed to read text from this file.",
+	"book.chapter":      "Chapter %d",
+	"book.progress":     "📖 “%s”\nVoicing chapter %d of %d: %s — %d%%",
+	"book.failed":       "📖 “%s”: failed to voice chapter %d, voicing is stopped.",
+	"book.paused":       "📖 “%s”: the bot is restarting, voicing is paused and will resume automatically.",
+
+	"transcribe.usage":        "Reply with /transcribe to a voice, audio or video message. You can set the speech language: /transcribe ru",
+	"transcribe.no_media":     "This message has no voice, audio or video.",
+	"transcribe.bad_language": "Unknown language \"%s\". Use a two-letter code, for example: /transcribe ru",
+
+	"format.choose":     "How should I send voiced text?",
+	"format.set":        "Voiced text will come as: %s.",
+	"format.voice":      "🎙 Voice message",
+	"format.mp3":        "🎵 MP3 audio file",
+	"format.m4a":        "🎵 M4A audio file",
+	"format.wav":        "📄 WAV file for editing",
+	"format.video_note": "⭕ Video message",
+
+	"language.choose": "Choose the interface language:",
+	"language.auto":   "Same as Telegram",
+	"language.set":    "Interface language: English.",
+	"language.reset":  "The language will follow your Telegram settings.",
+	"language.name":   "English",
+
+	"history.off":               "Voicing history is not kept. You can choose how long to keep it below.",
+	"history.entry":             "%d. %s · %s · %d s\n%s",
+	"history.btn_send":          "▶️ %d",
+	"history.btn_regenerate":    "🔄 %d",
+	"history.btn_settings":      "⚙️ Retention",
+	"history.btn_back":          "⬅️ Back",
+	"history.days_choose":       "How long should I keep your voicing history?",
+	"history.days_off":          "Don't keep",
+	"history.days_set":          "History is kept for: %s.",
+	"history.not_found":         "This entry is no longer in the history.",
+	"history.model_unavailable": "Model \"%s\" has been deleted, this entry can't be regenerated.",
+
+	"speech.btn_regenerate":  "🔄 Regenerate",
+	"speech.btn_model":       "🎙 Another model",
+	"speech.btn_slower":      "🐢 Slower",
+	"speech.btn_faster":      "🐇 Faster",
+	"speech.regenerating":    "Voicing again…",
+	"speech.speed_limit":     "The speed is already at its limit.",
+	"speech.no_other_models": "You have no other models. Create one with /save_model.",
+}
+
+var enPlurals = map[string][]string{
+	"model.limit": {
+		"You can save at most %d model.",
+		"You can save at most %d models.",
+	},
+	"models.title": {
+		"You have %d model out of %d. Choose one to manage:",
+		"You have %d models out of %d. Choose one to manage:",
+	},
+	"book.queued": {
+		"📖 “%s”: %d chapter is queued for voicing with model \"%s\". I will send the file once it is ready.",
+		"📖 “%s”: %d chapters are queued for voicing with model \"%s\". I will send the files as they are ready.",
+	},
+	"book.done": {
+		"📖 “%s” is voiced: %d chapter.",
+		"📖 “%s” is voiced: %d chapters.",
+	},
+	"history.title": {
+		"🕘 Voicing history for the last %d day. ▶️ — send again, 🔄 — regenerate.",
+		"🕘 Voicing history for the last %d days. ▶️ — send again, 🔄 — regenerate.",
+	},
+	"history.empty": {
+		"Nothing was voiced in the last %d day. Send me some text and it will show up here.",
+		"Nothing was voiced in the last %d days. Send me some text and it will show up here.",
+	},
+	"history.days": {
+		"%d day",
+		"%d days",
+	},
+}
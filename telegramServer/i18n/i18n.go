@@ -0,0 +1,123 @@
+package i18n
+
+import (
+	"fmt"
+	"gopkg.in/telebot.v3"
+	"strings"
+)
+
+const (
+	Russian = "ru"
+	English = "en"
+
+	// Default используется, когда Telegram не передал язык пользователя.
+	Default = Russian
+	// Fallback используется для языков, которых нет в каталоге.
+	Fallback = English
+
+	contextKey = "lang"
+)
+
+// Languages — поддерживаемые языки в порядке отображения.
+var Languages = []string{Russian, English}
+
+type catalog struct {
+	messages map[string]string
+	plurals  map[string][]string
+	plural   func(n int) int
+}
+
+var catalogs = map[string]catalog{
+	Russian: {messages: ruMessages, plurals: ruPlurals, plural: pluralRussian},
+	English: {messages: enMessages, plurals: enPlurals, plural: pluralEnglish},
+}
+
+// Resolve приводит код языка Telegram ("en-US", "ru") к языку каталога.
+func Resolve(code string) string {
+	if code == "" {
+		return Default
+	}
+
+	code = strings.ToLower(code)
+	if i := strings.IndexAny(code, "-_"); i > 0 {
+		code = code[:i]
+	}
+	if _, ok := catalogs[code]; ok {
+		return code
+	}
+	return Fallback
+}
+
+// T возвращает сообщение по ключу. Если перевода нет, используется
+// язык по умолчанию, а затем сам ключ.
+func T(lang string, key string, args ...any) string {
+	msg, ok := catalogs[lang].messages[key]
+	if !ok {
+		msg, ok = catalogs[Default].messages[key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// N возвращает форму сообщения для числа n по правилам множественного
+// числа языка. n не подставляется автоматически и передаётся в args.
+func N(lang string, key string, n int, args ...any) string {
+	cat, ok := catalogs[lang]
+	forms, found := cat.plurals[key]
+	if !ok || !found {
+		cat = catalogs[Default]
+		forms, found = cat.plurals[key]
+	}
+	if !found {
+		return key
+	}
+
+	form := cat.plural(n)
+	if form >= len(forms) {
+		form = len(forms) - 1
+	}
+	return fmt.Sprintf(forms[form], args...)
+}
+
+// SetLang сохраняет язык пользователя в контексте апдейта.
+func SetLang(c telebot.Context, lang string) {
+	c.Set(contextKey, lang)
+}
+
+// Lang возвращает язык, сохранённый SetLang, или определяет его по
+// настройкам Telegram, если middleware не отработал.
+func Lang(c telebot.Context) string {
+	if lang, ok := c.Get(contextKey).(string); ok {
+		return lang
+	}
+	if sender := c.Sender(); sender != nil {
+		return Resolve(sender.LanguageCode)
+	}
+	return Default
+}
+
+func pluralRussian(n int) int {
+	if n < 0 {
+		n = -n
+	}
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return 0
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return 1
+	default:
+		return 2
+	}
+}
+
+func pluralEnglish(n int) int {
+	if n == 1 {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,188 @@
+package i18n
+
+var ruMessages = map[string]string{
+	"command.save_model":    "Создать модель из голосового, аудио или видео.",
+	"command.models":        "Управление моделями: выбор, прослушивание, удаление.",
+	"command.current":       "Показать текущую модель.",
+	"command.format":        "Выбрать формат ответа: голосовое, MP3, WAV, видео.",
+	"command.history":       "История озвучки: повторить или переозвучить.",
+	"command.language":      "Выбрать язык интерфейса.",
+	"command.start":         "Старт",
+	"command.say":           "Озвучить текст: /say текст",
+	"command.transcribe":    "Распознать речь: ответь на голосовое /transcribe",
+	"command.chat_settings": "Настройки бота в этом чате.",
+
+	"start.text": `Привет! 👋
+
+Я помогу тебе создавать голосовые модели и генерировать аудио.
+
+Вот что ты можешь сделать:
+
+/save_model — создать новую голосовую модель
+/models — выбрать, прослушать, переименовать или удалить модели
+/current — показать текущую модель
+/format — выбрать, в каком виде присылать озвучку
+/history — история озвучки
+/language — сменить язык
+/say — озвучить текст, в группах тоже
+/transcribe — распознать речь в голосовом, аудио или видео
+/start — показать эту инструкцию ещё раз
+
+⚡ Просто напиши мне текст — я озвучу его выбранной моделью!
+🎙 Пришли голосовое — я перескажу его голосом выбранной модели.
+💬 В любом чате набери @имя_бота и текст, чтобы отправить озвучку.
+👥 В группе упомяни меня, ответь на моё сообщение или используй /say. Администратор может настроить меня через /chat_settings.
+📚 Пришли книгу в .txt, .md, .fb2 или .epub — я озвучу её по главам.`,
+
+	"error.generic":   "Возникла ошибка, повтори попытку позже.",
+	"error.busy":      "Сервис озвучки сейчас перезапускается или перегружен. Попробуй через минуту.",
+	"command.unknown": "Неизвестная команда.",
+
+	"callback.forged": "Некорректная кнопка.",
+	"callback.stale":  "Кнопка устарела, открой меню заново.",
+
+	"model.ask_name":        "Введи имя модели:",
+	"model.name_empty":      "Имя модели не может быть пустым.",
+	"model.name_invalid":    "Имя модели не может содержать \"/\", \"\\\", \"..\" и управляющие символы.",
+	"model.name_taken":      "У тебя уже есть модель с таким именем.",
+	"model.send_voice":      "Пришли голосовое, аудиофайл, видео или видеосообщение с голосом для создания модели.",
+	"model.not_found":       "Модель не найдена.",
+	"model.pending_missing": "Имя модели не найдено.",
+	"model.saved":           "Модель \"%s\" сохранена и выбрана для генерации.",
+	"model.rename_failed":   "Ошибка при переименовании модели.",
+	"model.renamed":         "Модель переименована в \"%s\".",
+	"model.required":        "Создай модель /save_model или выбери из сохранённых /models.",
+	"model.none_active":     "Модель не выбрана. Создай модель /save_model.",
+	"model.current":         "Текущая модель: \"%s\". Сменить её можно через /models.",
+	"voice.no_speech":       "Не удалось разобрать речь в голосовом сообщении.",
+	"voice.recognized":      "📝 Распознанный текст:\n<code>%s</code>\n\nЕсли что-то распознано неверно, пришли исправленный текст — я озвучу его заново.",
+	"sample.added":          "Образец добавлен к модели.",
+
+	"models.load_failed":    "Ошибка при получении моделей.",
+	"models.empty":          "Пока нет сохранённых моделей. Создай новую через /save_model.",
+	"models.selected":       "Выбрана модель: %s",
+	"models.sample_failed":  "Не удалось загрузить образец.",
+	"models.sample_caption": "Образец модели \"%s\"",
+	"models.ask_rename":     "Введи новое имя для модели \"%s\":",
+	"models.ask_sample":     "Пришли голосовое, аудиофайл, видео или видеосообщение, запись будет добавлена к образцу модели \"%s\".",
+	"models.delete_confirm": "Удалить модель \"%s\"? Это действие нельзя отменить.",
+	"models.delete_failed":  "Ошибка при удалении модели.",
+	"models.deleted":        "Модель успешно удалена.",
+	"models.deleted_active": "Модель успешно удалена. Текущая модель: %s",
+	"models.card":           "Модель \"%s\"",
+	"models.card_active":    "✅ Модель \"%s\" (текущая)",
+	"models.btn_select":     "Выбрать",
+	"models.btn_selected":   "✅ Выбрана",
+	"models.btn_preview":    "🔊 Прослушать",
+	"models.btn_rename":     "✏️ Переименовать",
+	"models.btn_sample":     "➕ Добавить образец",
+	"models.btn_delete":     "🗑 Удалить",
+	"models.btn_delete_yes": "🗑 Да, удалить",
+	"models.btn_cancel":     "Отмена",
+	"models.btn_back":       "⬅️ Назад",
+
+	"inline.hint":     "Напиши текст, и я озвучу его текущей моделью",
+	"inline.disabled": "Inline режим не настроен",
+	"inline.no_model": "Сначала создай модель в чате с ботом",
+	"inline.failed":   "Не удалось озвучить текст, попробуй ещё раз",
+	"inline.busy":     "Сервис озвучки занят, попробуй через минуту",
+
+	"say.empty":             "Напиши текст после команды (/say текст) или ответь командой /say на сообщение.",
+	"say.no_text":           "В этом сообщении нет текста для озвучки.",
+	"chat.group_only":       "Эта команда работает только в группах.",
+	"chat.not_admin":        "Это может сделать только администратор чата.",
+	"chat.admin_only":       "В этом чате озвучивать текст могут только администраторы.",
+	"chat.settings":         "Настройки чата\n\nКто может озвучивать: %s\nМодель: %s",
+	"chat.access_all":       "все участники",
+	"chat.access_admins":    "только администраторы",
+	"chat.model_own":        "у каждого своя текущая модель",
+	"chat.model_shared":     "общая модель \"%s\"",
+	"chat.btn_admin_only":   "🔒 Только администраторы",
+	"chat.btn_everyone":     "🔓 Все участники",
+	"chat.btn_use_my_model": "🎙 Сделать мою текущую модель общей",
+	"chat.btn_own_models":   "👥 Каждый со своей моделью",
+
+	"book.unsupported":  "Я озвучиваю книги в форматах .txt, .md, .fb2 и .epub.",
+	"file.too_large":    "Файл слишком большой: бот может скачать не больше %d МБ.",
+	"book.parse_failed": "Не удалось прочитать текст из этого файла.",
+	"book.chapter":      "Глава %d",
+	"book.progress":     "📖 «%s»\nОзвучиваю главу %d из %d: %s — %d%%",
+	"book.failed":       "📖 «%s»: не удалось озвучить главу %d, озвучка остановлена.",
+	"book.paused":       "📖 «%s»: бот перезапускается, озвучка приостановлена и продолжится автоматически.",
+
+	"transcribe.usage":        "Ответь командой /transcribe на голосовое, аудио или видеосообщение. Можно указать язык речи: /transcribe en",
+	"transcribe.no_media":     "В этом сообщении нет голосового, аудио или видео.",
+	"transcribe.bad_language": "Не понимаю язык \"%s\". Укажи двухбуквенный код, например: /transcribe en",
+
+	"format.choose":     "В каком виде присылать озвучку?",
+	"format.set":        "Озвучка будет приходить в формате: %s.",
+	"format.voice":      "🎙 Голосовое сообщение",
+	"format.mp3":        "🎵 Аудиофайл MP3",
+	"format.m4a":        "🎵 Аудиофайл M4A",
+	"format.wav":        "📄 Файл WAV для редактирования",
+	"format.video_note": "⭕ Видеосообщение",
+
+	"language.choose": "Выбери язык интерфейса:",
+	"language.auto":   "Как в Telegram",
+	"language.set":    "Язык интерфейса: русский.",
+	"language.reset":  "Язык будет определяться по настройкам Telegram.",
+	"language.name":   "Русский",
+
+	"history.off":               "История озвучки не сохраняется. Срок хранения можно выбрать кнопкой ниже.",
+	"history.entry":             "%d. %s · %s · %d с\n%s",
+	"history.btn_send":          "▶️ %d",
+	"history.btn_regenerate":    "🔄 %d",
+	"history.btn_settings":      "⚙️ Срок хранения",
+	"history.btn_back":          "⬅️ Назад",
+	"history.days_choose":       "Сколько хранить историю озвучки?",
+	"history.days_off":          "Не сохранять",
+	"history.days_set":          "Срок хранения истории: %s.",
+	"history.not_found":         "Этой записи уже нет в истории.",
+	"history.model_unavailable": "Модель \"%s\" удалена, переозвучить запись нельзя.",
+
+	"speech.btn_regenerate":  "🔄 Переозвучить",
+	"speech.btn_model":       "🎙 Другая модель",
+	"speech.btn_slower":      "🐢 Медленнее",
+	"speech.btn_faster":      "🐇 Быстрее",
+	"speech.regenerating":    "Озвучиваю заново…",
+	"speech.speed_limit":     "Темп уже на пределе.",
+	"speech.no_other_models": "Других моделей нет. Создай ещё одну через /save_model.",
+}
+
+var ruPlurals = map[string][]string{
+	"model.limit": {
+		"Можно сохранить не больше %d модели.",
+		"Можно сохранить не больше %d моделей.",
+		"Можно сохранить не больше %d моделей.",
+	},
+	"models.title": {
+		"У тебя %d модель из %d. Выбери модель для управления:",
+		"У тебя %d модели из %d. Выбери модель для управления:",
+		"У тебя %d моделей из %d. Выбери модель для управления:",
+	},
+	"book.queued": {
+		"📖 «%s»: %d глава в очереди на озвучку моделью \"%s\". Пришлю файл, как только он будет готов.",
+		"📖 «%s»: %d главы в очереди на озвучку моделью \"%s\". Пришлю файлы по мере готовности.",
+		"📖 «%s»: %d глав в очереди на озвучку моделью \"%s\". Пришлю файлы по мере готовности.",
+	},
+	"book.done": {
+		"📖 «%s» озвучена: %d глава.",
+		"📖 «%s» озвучена: %d главы.",
+		"📖 «%s» озвучена: %d глав.",
+	},
+	"history.title": {
+		"🕘 История озвучки за последний %d день. ▶️ — прислать ещё раз, 🔄 — переозвучить.",
+		"🕘 История озвучки за последние %d дня. ▶️ — прислать ещё раз, 🔄 — переозвучить.",
+		"🕘 История озвучки за последние %d дней. ▶️ — прислать ещё раз, 🔄 — переозвучить.",
+	},
+	"history.empty": {
+		"За последний %d день озвучек не было. Пришли текст, и он появится здесь.",
+		"За последние %d дня озвучек не было. Пришли текст, и он появится здесь.",
+		"За последние %d дней озвучек не было. Пришли текст, и он появится здесь.",
+	},
+	"history.days": {
+		"%d день",
+		"%d дня",
+		"%d дней",
+	},
+}
@@ -20,6 +20,7 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	AudioProcessor_ProcessContent_FullMethodName = "/audio_processing.v1.AudioProcessor/ProcessContent"
+	AudioProcessor_Transcribe_FullMethodName     = "/audio_processing.v1.AudioProcessor/Transcribe"
 )
 
 // AudioProcessorClient is the client API for AudioProcessor service.
@@ -27,6 +28,7 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type AudioProcessorClient interface {
 	ProcessContent(ctx context.Context, in *ContentRequest, opts ...grpc.CallOption) (*ProcessingResponse, error)
+	Transcribe(ctx context.Context, in *TranscribeRequest, opts ...grpc.CallOption) (*TranscribeResponse, error)
 }
 
 type audioProcessorClient struct {
@@ -47,11 +49,22 @@ func (c *audioProcessorClient) ProcessContent(ctx context.Context, in *ContentRe
 	return out, nil
 }
 
+func (c *audioProcessorClient) Transcribe(ctx context.Context, in *TranscribeRequest, opts ...grpc.CallOption) (*TranscribeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TranscribeResponse)
+	err := c.cc.Invoke(ctx, AudioProcessor_Transcribe_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AudioProcessorServer is the server API for AudioProcessor service.
 // All implementations must embed UnimplementedAudioProcessorServer
 // for forward compatibility.
 type AudioProcessorServer interface {
 	ProcessContent(context.Context, *ContentRequest) (*ProcessingResponse, error)
+	Transcribe(context.Context, *TranscribeRequest) (*TranscribeResponse, error)
 	mustEmbedUnimplementedAudioProcessorServer()
 }
 
@@ -65,6 +78,9 @@ type UnimplementedAudioProcessorServer struct{}
 func (UnimplementedAudioProcessorServer) ProcessContent(context.Context, *ContentRequest) (*ProcessingResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ProcessContent not implemented")
 }
+func (UnimplementedAudioProcessorServer) Transcribe(context.Context, *TranscribeRequest) (*TranscribeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transcribe not implemented")
+}
 func (UnimplementedAudioProcessorServer) mustEmbedUnimplementedAudioProcessorServer() {}
 func (UnimplementedAudioProcessorServer) testEmbeddedByValue()                        {}
 
@@ -104,6 +120,24 @@ func _AudioProcessor_ProcessContent_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AudioProcessor_Transcribe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TranscribeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AudioProcessorServer).Transcribe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AudioProcessor_Transcribe_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AudioProcessorServer).Transcribe(ctx, req.(*TranscribeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // AudioProcessor_ServiceDesc is the grpc.ServiceDesc for AudioProcessor service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -115,6 +149,10 @@ var AudioProcessor_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ProcessContent",
 			Handler:    _AudioProcessor_ProcessContent_Handler,
 		},
+		{
+			MethodName: "Transcribe",
+			Handler:    _AudioProcessor_Transcribe_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "audio_processor.proto",
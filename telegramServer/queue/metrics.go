@@ -0,0 +1,32 @@
+package queue
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	pendingJobs = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "queue_pending_jobs",
+		Help: "Задачи, которые ждут свободного воркера.",
+	})
+
+	runningJobs = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "queue_running_jobs",
+		Help: "Задачи, которые выполняются сейчас.",
+	})
+
+	waitDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "queue_wait_seconds",
+		Help:    "Время от постановки задачи в очередь до начала выполнения.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 3, 12),
+	})
+
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "queue_job_duration_seconds",
+		Help: "Длительность выполнения задач.",
+		// Глава книги озвучивается десятки минут.
+		Buckets: prometheus.ExponentialBuckets(0.5, 2, 14),
+	}, []string{"result"})
+)
+
+func init() {
+	prometheus.MustRegister(pendingJobs, runningJobs, waitDuration, jobDuration)
+}
@@ -0,0 +1,261 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"kursach/tracing"
+	"sync"
+	"time"
+)
+
+var tracer = otel.Tracer("kursach/queue")
+
+// Job — задача очереди. Run получает контекст, который отменяется при
+// остановке очереди.
+type Job struct {
+	Name string
+	Run  func(ctx context.Context) error
+	// Dropped, если задан, вызывается вместо Run, когда очередь
+	// остановилась раньше, чем задача началась, например, чтобы
+	// предупредить пользователя.
+	Dropped func(ctx context.Context)
+
+	enqueuedAt time.Time
+	// submitter — спан, который поставил задачу. Задача выполняется
+	// после его завершения, поэтому её спан связан с ним ссылкой, а не
+	// вложен в него.
+	submitter trace.SpanContext
+}
+
+// Queue выполняет задачи в фиксированном числе воркеров в порядке
+// поступления. Очередь не ограничена: длинные задачи, например,
+// озвучка книги, ставят следующий шаг в конец, чтобы не занимать
+// воркер надолго.
+type Queue struct {
+	workers int
+	log     *zap.Logger
+
+	mu     sync.Mutex
+	jobs   []Job
+	closed bool
+	notify chan struct{}
+	// draining закрывается при остановке, чтобы свободные воркеры
+	// завершились.
+	draining chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New(workers int, logger *zap.Logger) *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		workers:  workers,
+		log:      logger,
+		notify:   make(chan struct{}, 1),
+		draining: make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+func (q *Queue) Start() {
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	q.log.Info("Очередь задач запущена", zap.Int("workers", q.workers))
+}
+
+// Shutdown перестаёт брать задачи из очереди и ждёт завершения
+// выполняемых. Если ctx истекает раньше, их контекст отменяется.
+// Невыполненные задачи отбрасываются с вызовом Dropped, возвращается их
+// число: задачи должны уметь продолжиться после перезапуска, как
+// озвучка книг, состояние которой хранится в базе.
+func (q *Queue) Shutdown(ctx context.Context) int {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return 0
+	}
+	q.closed = true
+	jobs := q.jobs
+	q.jobs = nil
+	pendingJobs.Set(0)
+	q.mu.Unlock()
+	close(q.draining)
+
+	for _, job := range jobs {
+		q.drop(ctx, job)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		q.log.Warn("Задачи не завершились вовремя и будут прерваны")
+		q.cancel()
+		<-finished
+	}
+	q.cancel()
+	return len(jobs)
+}
+
+// Submit ставит задачу в очередь. Спан из ctx, если он есть, попадает
+// в трейс задачи ссылкой.
+func (q *Queue) Submit(ctx context.Context, job Job) {
+	job.enqueuedAt = time.Now()
+	job.submitter = trace.SpanContextFromContext(ctx)
+
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		q.drop(ctx, job)
+		return
+	}
+	q.jobs = append(q.jobs, job)
+	pendingJobs.Set(float64(len(q.jobs)))
+	q.mu.Unlock()
+
+	q.wake()
+	q.log.Debug("Задача поставлена в очередь", zap.String("job", job.Name))
+}
+
+// SubmitAfter ставит задачу в очередь через delay. Если очередь
+// останавливается раньше, задача отбрасывается. Shutdown дожидается
+// отложенных задач, чтобы Dropped успел выполниться.
+func (q *Queue) SubmitAfter(ctx context.Context, delay time.Duration, job Job) {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		q.drop(ctx, job)
+		return
+	}
+	q.wg.Add(1)
+	q.mu.Unlock()
+
+	timer := time.NewTimer(delay)
+	go func() {
+		defer q.wg.Done()
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			q.Submit(ctx, job)
+		case <-q.draining:
+			q.drop(ctx, job)
+		}
+	}()
+}
+
+// drop отбрасывает задачу, которую остановленная очередь не выполнит.
+func (q *Queue) drop(ctx context.Context, job Job) {
+	q.log.Info("Очередь остановлена, задача отброшена", zap.String("job", job.Name))
+	if job.Dropped != nil {
+		job.Dropped(ctx)
+	}
+}
+
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
+
+func (q *Queue) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) next() (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || len(q.jobs) == 0 {
+		return Job{}, false
+	}
+	job := q.jobs[0]
+	q.jobs[0] = Job{}
+	q.jobs = q.jobs[1:]
+	pendingJobs.Set(float64(len(q.jobs)))
+
+	// Разбудить следующий воркер, если задачи ещё остались.
+	if len(q.jobs) > 0 {
+		q.wake()
+	}
+	return job, true
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for {
+		if q.ctx.Err() != nil {
+			return
+		}
+
+		job, ok := q.next()
+		if !ok {
+			select {
+			case <-q.notify:
+				continue
+			case <-q.draining:
+				return
+			case <-q.ctx.Done():
+				return
+			}
+		}
+
+		q.run(job)
+	}
+}
+
+func (q *Queue) run(job Job) {
+	started := time.Now()
+	waitDuration.Observe(started.Sub(job.enqueuedAt).Seconds())
+	runningJobs.Inc()
+
+	opts := []trace.SpanStartOption{trace.WithAttributes(
+		attribute.String("queue.job", job.Name),
+		attribute.Float64("queue.wait_seconds", started.Sub(job.enqueuedAt).Seconds()),
+	)}
+	if job.submitter.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: job.submitter}))
+	}
+	ctx, span := tracer.Start(q.ctx, "queue.job", opts...)
+
+	var err error
+	result := "panic"
+	defer func() {
+		runningJobs.Dec()
+		jobDuration.WithLabelValues(result).Observe(time.Since(started).Seconds())
+		if r := recover(); r != nil {
+			q.log.Error("Паника в задаче очереди", zap.String("job", job.Name), zap.Any("panic", r))
+			err = fmt.Errorf("паника в задаче: %v", r)
+		}
+		tracing.End(span, err)
+	}()
+
+	err = job.Run(ctx)
+	if err != nil {
+		result = "error"
+		q.log.Error("Ошибка выполнения задачи", zap.String("job", job.Name), zap.Error(err))
+		return
+	}
+	result = "ok"
+	q.log.Info("Задача выполнена",
+		zap.String("job", job.Name),
+		zap.Duration("wait", started.Sub(job.enqueuedAt)),
+		zap.Duration("duration", time.Since(started)),
+	)
+}
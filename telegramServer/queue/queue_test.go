@@ -0,0 +1,65 @@
+package queue
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestShutdownDropsPendingJobs(t *testing.T) {
+	q := New(1, zap.NewNop())
+	q.Start()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var ran, dropped atomic.Int32
+	q.Submit(context.Background(), Job{
+		Name: "busy",
+		Run: func(ctx context.Context) error {
+			close(started)
+			<-release
+			ran.Add(1)
+			return nil
+		},
+		Dropped: func(ctx context.Context) { t.Error("выполняемая задача отброшена") },
+	})
+	<-started
+
+	job := Job{
+		Name: "pending",
+		Run: func(ctx context.Context) error {
+			ran.Add(1)
+			return nil
+		},
+		Dropped: func(ctx context.Context) { dropped.Add(1) },
+	}
+	q.Submit(context.Background(), job)
+	q.SubmitAfter(context.Background(), time.Hour, job)
+
+	// Занятый воркер освобождается, только когда Shutdown забрал
+	// задачи из очереди.
+	go func() {
+		for q.Len() > 0 {
+			time.Sleep(time.Millisecond)
+		}
+		close(release)
+	}()
+	if n := q.Shutdown(context.Background()); n != 1 {
+		t.Fatalf("отброшено %d задач из очереди, ожидалась 1", n)
+	}
+	if got := dropped.Load(); got != 2 {
+		t.Fatalf("Dropped вызван %d раз, ожидалось 2", got)
+	}
+	if got := ran.Load(); got != 1 {
+		t.Fatalf("выполнено %d задач, ожидалась 1", got)
+	}
+
+	// После остановки задача сразу отбрасывается.
+	q.Submit(context.Background(), job)
+	if got := dropped.Load(); got != 3 {
+		t.Fatalf("Dropped вызван %d раз, ожидалось 3", got)
+	}
+}
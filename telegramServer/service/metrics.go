@@ -0,0 +1,39 @@
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"kursach/defs"
+	"strconv"
+	"time"
+)
+
+// Метки model — ID моделей голоса: имена задают пользователи, и у
+// разных пользователей они совпадают.
+var (
+	synthesisDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tts_synthesis_duration_seconds",
+		Help:    "Длительность успешного синтеза речи в AudioProcessor, включая повторы.",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 13),
+	}, []string{"model"})
+
+	audioProduced = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tts_audio_seconds_total",
+		Help: "Длительность синтезированной речи в секундах.",
+	}, []string{"model"})
+)
+
+func init() {
+	prometheus.MustRegister(synthesisDuration, audioProduced)
+}
+
+func modelLabel(model defs.Model) string {
+	return strconv.FormatInt(model.ID, 10)
+}
+
+func observeSynthesis(model defs.Model, start time.Time) {
+	synthesisDuration.WithLabelValues(modelLabel(model)).Observe(time.Since(start).Seconds())
+}
+
+func observeAudio(model defs.Model, d time.Duration) {
+	audioProduced.WithLabelValues(modelLabel(model)).Add(d.Seconds())
+}
@@ -1,36 +1,89 @@
 package service
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"io"
+	"kursach/audio"
+	"kursach/book"
+	"kursach/cache"
 	"kursach/defs"
 	pb "kursach/proto"
-	"net/http"
+	"kursach/tracing"
 	"os"
 	"path/filepath"
 	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
+var tracer = otel.Tracer("kursach/service")
+
 type AudioProcessorClient interface {
-	SendAudio(text string, audioData []byte) (*pb.ProcessingResponse, error)
+	SendAudio(ctx context.Context, text string, audioData []byte, speed float64) (*pb.ProcessingResponse, error)
+	Transcribe(ctx context.Context, audioData []byte, language string) (*pb.TranscribeResponse, error)
 }
 
 type Storage interface {
-	IsUserExists(userId int64) (bool, error)
-	AddUser(userId int64) error
-	SaveModel(userID int64, modelName string) error
-	GetUserModels(userID int64) ([]string, error)
-	CountModels(userID int64) (int, error)
-	DeleteModel(userID int64, modelName string) error
+	IsUserExists(ctx context.Context, userId int64) (bool, error)
+	AddUser(ctx context.Context, userId int64) error
+	SaveModel(ctx context.Context, userID int64, modelName string) (int64, error)
+	GetUserModels(ctx context.Context, userID int64) ([]defs.Model, error)
+	GetModel(ctx context.Context, userID int64, modelID int64) (defs.Model, error)
+	CountModels(ctx context.Context, userID int64) (int, error)
+	RenameModel(ctx context.Context, userID int64, modelID int64, newName string) error
+	DeleteModel(ctx context.Context, userID int64, modelID int64) error
+	SetActiveModel(ctx context.Context, userID int64, modelID int64) error
+	GetActiveModel(ctx context.Context, userID int64) (defs.Model, error)
+	SetUserLanguage(ctx context.Context, userID int64, lang string) error
+	GetUserLanguage(ctx context.Context, userID int64) (string, error)
+	SetOutputFormat(ctx context.Context, userID int64, format string) error
+	GetOutputFormat(ctx context.Context, userID int64) (string, error)
+	AddGeneration(ctx context.Context, g defs.Generation) (int64, error)
+	GetGenerations(ctx context.Context, userID int64, days int, offset int, limit int) ([]defs.Generation, error)
+	CountGenerations(ctx context.Context, userID int64, days int) (int, error)
+	GetGeneration(ctx context.Context, userID int64, generationID int64) (defs.Generation, error)
+	DeleteOldGenerations(ctx context.Context, userID int64, days int) error
+	SetHistoryDays(ctx context.Context, userID int64, days int) error
+	GetHistoryDays(ctx context.Context, userID int64) (int, error)
+	GetChatSettings(ctx context.Context, chatID int64) (defs.ChatSettings, error)
+	SetChatAdminOnly(ctx context.Context, chatID int64, adminOnly bool) error
+	SetChatModel(ctx context.Context, chatID int64, modelID int64) error
+	CreateAudiobook(ctx context.Context, book defs.Audiobook, chapters []defs.Chapter, owner string, lease time.Duration) (int64, error)
+	GetAudiobook(ctx context.Context, bookID int64) (defs.Audiobook, error)
+	ClaimAudiobooks(ctx context.Context, owner string, lease time.Duration, reclaimOwn bool) ([]int64, error)
+	ClaimAudiobook(ctx context.Context, bookID int64, owner string, lease time.Duration) (bool, error)
+	ReleaseAudiobooks(ctx context.Context, owner string) (int, error)
+	SetAudiobookMessage(ctx context.Context, bookID int64, messageID int) error
+	SetAudiobookStatus(ctx context.Context, bookID int64, status string) error
+	GetNextChapter(ctx context.Context, bookID int64) (defs.Chapter, bool, error)
+	MarkChapterDone(ctx context.Context, bookID int64, index int) error
 }
 
 type Service struct {
 	audioProcessorClient AudioProcessorClient
 	storage              Storage
 	log                  *zap.Logger
-	userStates           map[int64]string
-	activeModels         map[int64]string
+
+	// Состояния диалогов хранятся по паре (чат, пользователь), чтобы
+	// диалог в одной группе не влиял на другие чаты.
+	mu            sync.Mutex
+	userStates    map[defs.Scope]string
+	pendingModels map[defs.Scope]string
+	editingModels map[defs.Scope]int64
+
+	speechCache *cache.LRU[string, *cachedSpeech]
+
+	// instance — имя копии бота, за которой закрепляются книги.
+	instance string
 }
 
 func NewService(client AudioProcessorClient, storage Storage, logger *zap.Logger) *Service {
@@ -38,55 +91,71 @@ func NewService(client AudioProcessorClient, storage Storage, logger *zap.Logger
 		audioProcessorClient: client,
 		storage:              storage,
 		log:                  logger,
-		userStates:           make(map[int64]string),
-		activeModels:         make(map[int64]string),
+		userStates:           make(map[defs.Scope]string),
+		pendingModels:        make(map[defs.Scope]string),
+		editingModels:        make(map[defs.Scope]int64),
+		speechCache: cache.NewSizedLRU[string, *cachedSpeech](defs.SpeechCacheSize, defs.SpeechCacheBytes, func(cached *cachedSpeech) int64 {
+			return int64(len(cached.speech.Opus))
+		}),
+		instance: instanceName(),
 	}
 }
 
-func (s *Service) SaveModel(userID int64, fileInfo string, token string, modelName string) error {
-	s.log.Info("Сохранение новой модели", zap.Int64("userID", userID), zap.String("modelName", modelName))
-
-	userDir := filepath.Join("voices", fmt.Sprintf("%d", userID))
-	if err := os.MkdirAll(userDir, 0755); err != nil {
-		s.log.Error("Ошибка создания директории для модели", zap.String("path", userDir), zap.Error(err))
-		return fmt.Errorf("не удалось создать директорию: %w", err)
+// instanceName возвращает имя хоста и PID процесса. Имя контейнера при
+// перезапуске сохраняется, поэтому копия бота продолжает свои книги
+// сразу, не дожидаясь истечения аренды.
+func instanceName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
 	}
+	return host + ":" + strconv.Itoa(os.Getpid())
+}
 
-	filePath := filepath.Join(userDir, fmt.Sprintf("%s.ogg", modelName))
-	fileURL := fmt.Sprintf("https://api.telegram.org/file/bot%s/%s", token, fileInfo)
+// SaveModel сохраняет образец голоса из голосового, аудио или видео.
+// Звуковая дорожка извлекается и нормализуется перед сохранением.
+func (s *Service) SaveModel(ctx context.Context, userID int64, sample []byte, modelName string) (err error) {
+	ctx, span := tracer.Start(ctx, "service.SaveModel", trace.WithAttributes(attribute.Int("audio.input_bytes", len(sample))))
+	defer func() { tracing.End(span, err) }()
 
-	resp, err := http.Get(fileURL)
-	if err != nil {
-		s.log.Error("Ошибка загрузки файла с сервера Telegram", zap.Error(err))
-		return fmt.Errorf("ошибка загрузки файла: %w", err)
+	s.log.Info("Сохранение новой модели", zap.Int64("userID", userID), zap.String("modelName", modelName))
+	if !defs.ValidModelName(modelName) {
+		return defs.ErrInvalidModelName{}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		s.log.Error("Сервер Telegram вернул ошибку", zap.Int("status_code", resp.StatusCode))
-		return fmt.Errorf("telegram API вернул статус %d", resp.StatusCode)
+	normalized, err := audio.NormalizeSample(ctx, sample)
+	if err != nil {
+		s.log.Error("Ошибка нормализации образца", zap.String("modelName", modelName), zap.Error(err))
+		return err
 	}
 
-	outFile, err := os.Create(filePath)
-	if err != nil {
-		s.log.Error("Ошибка создания локального файла модели", zap.String("path", filePath), zap.Error(err))
-		return fmt.Errorf("ошибка создания файла: %w", err)
+	userDir := filepath.Join("voices", fmt.Sprintf("%d", userID))
+	if err := os.MkdirAll(userDir, 0755); err != nil {
+		s.log.Error("Ошибка создания директории для модели", zap.String("path", userDir), zap.Error(err))
+		return fmt.Errorf("не удалось создать директорию: %w", err)
 	}
-	defer outFile.Close()
 
-	_, err = io.Copy(outFile, resp.Body)
+	// Образец занимает место файла модели только после сохранения
+	// строки в БД: файл модели с тем же именем не перезаписывается, а
+	// при ошибке не остаётся лишнего файла.
+	tmpPath, err := writeTempFile(userDir, normalized)
 	if err != nil {
-		s.log.Error("Ошибка записи содержимого файла", zap.String("path", filePath), zap.Error(err))
+		s.log.Error("Ошибка записи файла модели", zap.String("path", userDir), zap.Error(err))
 		return fmt.Errorf("ошибка записи файла: %w", err)
 	}
+	defer func() {
+		if err := os.Remove(tmpPath); err != nil && !os.IsNotExist(err) {
+			s.log.Warn("Не удалось удалить временный файл модели", zap.String("path", tmpPath), zap.Error(err))
+		}
+	}()
 
-	ok, err := s.storage.IsUserExists(userID)
+	ok, err := s.storage.IsUserExists(ctx, userID)
 	if err != nil {
 		s.log.Error("Ошибка проверки существования пользователя", zap.Error(err))
 		return err
 	}
 	if !ok {
-		err = s.storage.AddUser(userID)
+		err = s.storage.AddUser(ctx, userID)
 		if err != nil {
 			s.log.Error("Ошибка добавления нового пользователя в БД", zap.Error(err))
 			return err
@@ -94,52 +163,149 @@ func (s *Service) SaveModel(userID int64, fileInfo string, token string, modelNa
 		s.log.Info("Пользователь успешно добавлен в БД", zap.Int64("userID", userID))
 	}
 
-	err = s.storage.SaveModel(userID, modelName)
+	modelID, err := s.storage.SaveModel(ctx, userID, modelName)
 	if err != nil {
 		s.log.Error("Ошибка сохранения модели в БД", zap.Error(err))
 		return err
 	}
 
+	filePath := modelFilePath(userID, modelName)
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		s.log.Error("Ошибка переименования файла модели", zap.String("path", filePath), zap.Error(err))
+		if deleteErr := s.storage.DeleteModel(ctx, userID, modelID); deleteErr != nil {
+			s.log.Error("Ошибка удаления модели без файла", zap.Int64("modelID", modelID), zap.Error(deleteErr))
+		}
+		return fmt.Errorf("ошибка записи файла: %w", err)
+	}
+
+	err = s.storage.SetActiveModel(ctx, userID, modelID)
+	if err != nil {
+		s.log.Error("Ошибка выбора новой модели активной", zap.Error(err))
+		return err
+	}
+
 	s.log.Info("Модель успешно сохранена", zap.String("modelPath", filePath))
 	return nil
 }
 
-func (s *Service) GetUserState(userID int64) (string, error) {
-	state := s.userStates[userID]
-	s.log.Debug("Получение состояния пользователя", zap.Int64("userID", userID), zap.String("state", state))
+func (s *Service) GetUserState(scope defs.Scope) (string, error) {
+	s.mu.Lock()
+	state := s.userStates[scope]
+	s.mu.Unlock()
+
+	s.log.Debug("Получение состояния пользователя", zap.Int64("chatID", scope.ChatID), zap.Int64("userID", scope.UserID), zap.String("state", state))
 	return state, nil
 }
 
-func (s *Service) SetUserState(userID int64, state string) error {
-	s.userStates[userID] = state
-	s.log.Debug("Установка состояния пользователя", zap.Int64("userID", userID), zap.String("newState", state))
+func (s *Service) SetUserState(scope defs.Scope, state string) error {
+	s.mu.Lock()
+	s.userStates[scope] = state
+	s.mu.Unlock()
+
+	s.log.Debug("Установка состояния пользователя", zap.Int64("chatID", scope.ChatID), zap.Int64("userID", scope.UserID), zap.String("newState", state))
 	return nil
 }
 
-func (s *Service) SetPendingModel(userID int64, name string) error {
-	s.activeModels[userID] = name
-	s.log.Debug("Установка pending модели", zap.Int64("userID", userID), zap.String("modelName", name))
+func (s *Service) SetPendingModel(scope defs.Scope, name string) error {
+	s.mu.Lock()
+	s.pendingModels[scope] = name
+	s.mu.Unlock()
+
+	s.log.Debug("Установка pending модели", zap.Int64("chatID", scope.ChatID), zap.Int64("userID", scope.UserID), zap.String("modelName", name))
 	return nil
 }
 
-func (s *Service) GetModelName(userID int64) (string, error) {
-	modelName := s.activeModels[userID]
+func (s *Service) GetModelName(scope defs.Scope) (string, error) {
+	s.mu.Lock()
+	modelName := s.pendingModels[scope]
+	s.mu.Unlock()
+
 	if modelName == "" {
-		s.log.Warn("Модель не найдена у пользователя", zap.Int64("userID", userID))
+		s.log.Warn("Модель не найдена у пользователя", zap.Int64("chatID", scope.ChatID), zap.Int64("userID", scope.UserID))
 		return "", fmt.Errorf("no model names: %w", defs.ErrNoModel{})
 	}
-	s.log.Debug("Получение имени pending модели", zap.Int64("userID", userID), zap.String("modelName", modelName))
+	s.log.Debug("Получение имени pending модели", zap.Int64("userID", scope.UserID), zap.String("modelName", modelName))
 	return modelName, nil
 }
 
-func (s *Service) SendAudio(userID int64, text string) (*pb.ProcessingResponse, error) {
-	modelName, err := s.GetModelName(userID)
+func (s *Service) SelectModel(ctx context.Context, userID int64, modelID int64) error {
+	model, err := s.GetModel(ctx, userID, modelID)
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.SetActiveModel(ctx, userID, model.ID)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("Выбрана активная модель", zap.Int64("userID", userID), zap.String("modelName", model.Name))
+	return nil
+}
+
+// GetActiveModel возвращает активную модель пользователя. Если она не
+// выбрана или была удалена, активной становится самая новая из
+// оставшихся моделей.
+func (s *Service) GetActiveModel(ctx context.Context, userID int64) (defs.Model, error) {
+	model, err := s.storage.GetActiveModel(ctx, userID)
+	if err == nil {
+		return model, nil
+	}
+	if !errors.Is(err, defs.ErrNoModel{}) {
+		s.log.Error("Ошибка получения активной модели", zap.Error(err))
+		return defs.Model{}, err
+	}
+
+	models, err := s.storage.GetUserModels(ctx, userID)
+	if err != nil {
+		s.log.Error("Ошибка получения списка моделей пользователя", zap.Error(err))
+		return defs.Model{}, err
+	}
+	if len(models) == 0 {
+		return defs.Model{}, fmt.Errorf("no models: %w", defs.ErrNoModel{})
+	}
+
+	model = models[len(models)-1]
+	err = s.storage.SetActiveModel(ctx, userID, model.ID)
+	if err != nil {
+		return defs.Model{}, err
+	}
+
+	s.log.Info("Активная модель выбрана автоматически", zap.Int64("userID", userID), zap.String("modelName", model.Name))
+	return model, nil
+}
+
+func (s *Service) SendAudio(ctx context.Context, userID int64, text string) (*pb.ProcessingResponse, error) {
+	model, err := s.GetActiveModel(ctx, userID)
 	if err != nil {
 		s.log.Error("Ошибка получения модели для отправки аудио", zap.Error(err))
 		return nil, err
 	}
 
-	modelPath := filepath.Join("voices", strconv.FormatInt(userID, 10), modelName+".ogg")
+	return s.SendAudioWithModel(ctx, userID, model, text, defs.NormalSpeed)
+}
+
+// ResolveModel выбирает модель для синтеза в чате: общую модель чата,
+// если она задана, иначе активную модель пользователя.
+func (s *Service) ResolveModel(ctx context.Context, scope defs.Scope) (defs.Model, error) {
+	if scope.ChatID != scope.UserID {
+		settings, err := s.storage.GetChatSettings(ctx, scope.ChatID)
+		if err != nil {
+			return defs.Model{}, err
+		}
+		if settings.Model.ID != 0 {
+			return settings.Model, nil
+		}
+	}
+
+	return s.GetActiveModel(ctx, scope.UserID)
+}
+
+// SendAudioWithModel синтезирует текст моделью model в темпе speed.
+// Модель может принадлежать другому пользователю, например, быть общей
+// моделью чата.
+func (s *Service) SendAudioWithModel(ctx context.Context, userID int64, model defs.Model, text string, speed float64) (*pb.ProcessingResponse, error) {
+	modelPath := modelFilePath(model.UserID, model.Name)
 	if _, err := os.Stat(modelPath); os.IsNotExist(err) {
 		s.log.Error("Файл модели не найден", zap.String("modelPath", modelPath))
 		return nil, err
@@ -151,18 +317,150 @@ func (s *Service) SendAudio(userID int64, text string) (*pb.ProcessingResponse,
 		return nil, err
 	}
 
-	audio, err := s.audioProcessorClient.SendAudio(text, modelBytes)
+	start := time.Now()
+	audio, err := s.audioProcessorClient.SendAudio(ctx, text, modelBytes, speed)
 	if err != nil {
 		s.log.Error("Ошибка отправки аудио в AudioProcessor", zap.Error(err))
 		return nil, err
 	}
+	if audio.GetStatus() == "OK" {
+		observeSynthesis(model, start)
+	}
 
 	s.log.Info("Успешно отправлено аудио на обработку", zap.Int64("userID", userID))
 	return audio, nil
 }
 
-func (s *Service) GetUserModels(userID int64) ([]string, error) {
-	models, err := s.storage.GetUserModels(userID)
+// Transcribe распознаёт речь в аудио или видео любого формата, который
+// понимает ffmpeg на стороне AudioProcessor. language — подсказка языка
+// речи, пустая строка означает автоматическое определение.
+func (s *Service) Transcribe(ctx context.Context, userID int64, audioData []byte, language string) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "service.Transcribe", trace.WithAttributes(
+		attribute.Int("audio.input_bytes", len(audioData)),
+		attribute.String("transcribe.language", language),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	res, err := s.audioProcessorClient.Transcribe(ctx, audioData, language)
+	if err != nil {
+		s.log.Error("Ошибка распознавания речи в AudioProcessor", zap.Error(err))
+		return "", err
+	}
+	if res.GetStatus() != "OK" {
+		s.log.Error("AudioProcessor не распознал речь", zap.String("status", res.GetStatus()))
+		return "", fmt.Errorf("AudioProcessor вернул статус %q", res.GetStatus())
+	}
+
+	s.log.Info("Речь распознана", zap.Int64("userID", userID), zap.String("language", language), zap.Int("textLen", len(res.GetText())))
+	return res.GetText(), nil
+}
+
+// cachedSpeech — запись кэша синтеза: голосовое сообщение и file_id,
+// полученные после первой отправки в каждом из форматов.
+type cachedSpeech struct {
+	speech defs.Speech
+
+	mu      sync.Mutex
+	fileIDs map[string]string
+}
+
+// Synthesize озвучивает text моделью model в темпе speed. Результат
+// кэшируется по модели, версии образца, темпу и тексту, поэтому
+// повторный запрос не обращается к AudioProcessor.
+func (s *Service) Synthesize(ctx context.Context, userID int64, model defs.Model, text string, speed float64) (_ defs.Speech, err error) {
+	ctx, span := tracer.Start(ctx, "service.Synthesize", trace.WithAttributes(
+		attribute.Int64("tts.model_id", model.ID),
+		attribute.Int("tts.text_runes", utf8.RuneCountInString(text)),
+		attribute.Float64("tts.speed", speed),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	key, err := speechKey(model, text, speed)
+	if err != nil {
+		s.log.Error("Ошибка построения ключа кэша", zap.Error(err))
+		return defs.Speech{}, err
+	}
+
+	cached, ok := s.speechCache.Get(key)
+	span.SetAttributes(attribute.Bool("tts.cached", ok))
+	if ok {
+		s.log.Info("Озвучка найдена в кэше", zap.Int64("userID", userID), zap.Int64("modelID", model.ID))
+		return cached.speech, nil
+	}
+
+	res, err := s.SendAudioWithModel(ctx, userID, model, text, speed)
+	if err != nil {
+		return defs.Speech{}, err
+	}
+	if res.GetStatus() != "OK" {
+		s.log.Error("AudioProcessor не озвучил текст", zap.String("status", res.GetStatus()))
+		return defs.Speech{}, fmt.Errorf("AudioProcessor вернул статус %q", res.GetStatus())
+	}
+
+	opus, dur, err := audio.ToVoice(ctx, res.GetResult().GetProcessedAudio())
+	if err != nil {
+		s.log.Error("Ошибка перекодировки аудио", zap.Error(err))
+		return defs.Speech{}, err
+	}
+
+	observeAudio(model, time.Duration(dur)*time.Second)
+
+	speech := defs.Speech{Key: key, ModelID: model.ID, Speed: speed, Opus: opus, Duration: dur}
+	s.speechCache.Put(key, &cachedSpeech{speech: speech, fileIDs: make(map[string]string)})
+	return speech, nil
+}
+
+// Regenerate озвучивает text заново, не используя кэш, и заменяет
+// результат в кэше новым.
+func (s *Service) Regenerate(ctx context.Context, userID int64, model defs.Model, text string, speed float64) (defs.Speech, error) {
+	key, err := speechKey(model, text, speed)
+	if err != nil {
+		s.log.Error("Ошибка построения ключа кэша", zap.Error(err))
+		return defs.Speech{}, err
+	}
+	s.speechCache.Remove(key)
+
+	return s.Synthesize(ctx, userID, model, text, speed)
+}
+
+// CachedFileID возвращает file_id озвучки key, уже отправленной в
+// формате format.
+func (s *Service) CachedFileID(key string, format string) (string, bool) {
+	cached, ok := s.speechCache.Get(key)
+	if !ok {
+		return "", false
+	}
+
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+	fileID, ok := cached.fileIDs[format]
+	return fileID, ok
+}
+
+func (s *Service) CacheFileID(key string, format string, fileID string) {
+	cached, ok := s.speechCache.Get(key)
+	if !ok {
+		return
+	}
+
+	cached.mu.Lock()
+	cached.fileIDs[format] = fileID
+	cached.mu.Unlock()
+}
+
+// invalidateSpeech удаляет из кэша озвучки модели, например, после
+// изменения её образца.
+func (s *Service) invalidateSpeech(modelID int64) {
+	removed := s.speechCache.RemoveFunc(func(_ string, cached *cachedSpeech) bool {
+		return cached.speech.ModelID == modelID
+	})
+	if removed > 0 {
+		s.log.Info("Кэш озвучки модели сброшен", zap.Int64("modelID", modelID), zap.Int("entries", removed))
+	}
+}
+
+func (s *Service) GetUserModels(ctx context.Context, userID int64) ([]defs.Model, error) {
+	models, err := s.storage.GetUserModels(ctx, userID)
 	if err != nil {
 		s.log.Error("Ошибка получения списка моделей пользователя", zap.Error(err))
 		return nil, err
@@ -170,8 +468,19 @@ func (s *Service) GetUserModels(userID int64) ([]string, error) {
 	return models, nil
 }
 
-func (s *Service) CountModels(userID int64) (int, error) {
-	count, err := s.storage.CountModels(userID)
+func (s *Service) GetModel(ctx context.Context, userID int64, modelID int64) (defs.Model, error) {
+	model, err := s.storage.GetModel(ctx, userID, modelID)
+	if err != nil {
+		if !errors.Is(err, defs.ErrNoModel{}) {
+			s.log.Error("Ошибка получения модели", zap.Int64("modelID", modelID), zap.Error(err))
+		}
+		return defs.Model{}, err
+	}
+	return model, nil
+}
+
+func (s *Service) CountModels(ctx context.Context, userID int64) (int, error) {
+	count, err := s.storage.CountModels(ctx, userID)
 	if err != nil {
 		s.log.Error("Ошибка подсчёта моделей пользователя", zap.Error(err))
 		return 0, err
@@ -179,6 +488,460 @@ func (s *Service) CountModels(userID int64) (int, error) {
 	return count, nil
 }
 
-func (s *Service) DeleteModel(userID int64, modelName string) error {
-	return s.storage.DeleteModel(userID, modelName)
+// RenameModel переименовывает файл образца, затем запись в базе. Если
+// запись не обновилась, файл возвращается на место, чтобы модель не
+// ссылалась на несуществующий файл.
+func (s *Service) RenameModel(ctx context.Context, userID int64, modelID int64, newName string) error {
+	if !defs.ValidModelName(newName) {
+		return defs.ErrInvalidModelName{}
+	}
+
+	model, err := s.GetModel(ctx, userID, modelID)
+	if err != nil {
+		return err
+	}
+
+	oldPath, newPath := modelFilePath(userID, model.Name), modelFilePath(userID, newName)
+	if oldPath != newPath {
+		if _, err := os.Lstat(newPath); err == nil {
+			return fmt.Errorf("файл модели %q уже существует", newName)
+		}
+		if err := os.Rename(oldPath, newPath); err != nil {
+			s.log.Error("Ошибка переименования файла модели", zap.String("modelName", model.Name), zap.Error(err))
+			return fmt.Errorf("ошибка переименования файла модели: %w", err)
+		}
+	}
+
+	err = s.storage.RenameModel(ctx, userID, modelID, newName)
+	if err != nil {
+		if oldPath != newPath {
+			if restoreErr := os.Rename(newPath, oldPath); restoreErr != nil {
+				s.log.Error("Ошибка возврата файла модели", zap.String("modelName", model.Name), zap.Error(restoreErr))
+			}
+		}
+		return err
+	}
+
+	s.log.Info("Модель переименована", zap.Int64("userID", userID), zap.String("oldName", model.Name), zap.String("newName", newName))
+	return nil
+}
+
+func (s *Service) DeleteModel(ctx context.Context, userID int64, modelID int64) error {
+	model, err := s.GetModel(ctx, userID, modelID)
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.DeleteModel(ctx, userID, modelID)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(modelFilePath(userID, model.Name))
+	if err != nil && !os.IsNotExist(err) {
+		s.log.Warn("Не удалось удалить файл модели", zap.String("modelName", model.Name), zap.Error(err))
+	}
+
+	s.invalidateSpeech(modelID)
+	s.log.Info("Модель удалена", zap.Int64("userID", userID), zap.String("modelName", model.Name))
+	return nil
+}
+
+func (s *Service) GetModelSample(ctx context.Context, userID int64, modelID int64) ([]byte, error) {
+	model, err := s.GetModel(ctx, userID, modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	sample, err := os.ReadFile(modelFilePath(userID, model.Name))
+	if err != nil {
+		s.log.Error("Ошибка чтения файла модели", zap.String("modelName", model.Name), zap.Error(err))
+		return nil, fmt.Errorf("ошибка чтения файла модели: %w", err)
+	}
+	return sample, nil
+}
+
+// AddSample дописывает новую запись голоса в конец образца модели,
+// чтобы у XTTS было больше материала для клонирования.
+func (s *Service) AddSample(ctx context.Context, userID int64, modelID int64, sample []byte) (err error) {
+	ctx, span := tracer.Start(ctx, "service.AddSample", trace.WithAttributes(
+		attribute.Int64("tts.model_id", modelID),
+		attribute.Int("audio.input_bytes", len(sample)),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	model, err := s.GetModel(ctx, userID, modelID)
+	if err != nil {
+		return err
+	}
+
+	normalized, err := audio.NormalizeSample(ctx, sample)
+	if err != nil {
+		s.log.Error("Ошибка нормализации образца", zap.String("modelName", model.Name), zap.Error(err))
+		return err
+	}
+
+	modelPath := modelFilePath(userID, model.Name)
+	samplePath := modelPath + ".new"
+	if err := os.WriteFile(samplePath, normalized, 0644); err != nil {
+		s.log.Error("Ошибка записи файла образца", zap.String("path", samplePath), zap.Error(err))
+		return fmt.Errorf("ошибка записи файла: %w", err)
+	}
+	defer os.Remove(samplePath)
+
+	merged, err := audio.Concat(ctx, modelPath, samplePath)
+	if err != nil {
+		s.log.Error("Ошибка склейки образцов модели", zap.String("modelName", model.Name), zap.Error(err))
+		return err
+	}
+
+	err = os.WriteFile(modelPath, merged, 0644)
+	if err != nil {
+		s.log.Error("Ошибка записи файла модели", zap.String("path", modelPath), zap.Error(err))
+		return fmt.Errorf("ошибка записи файла: %w", err)
+	}
+
+	s.invalidateSpeech(modelID)
+	s.log.Info("Образец добавлен к модели", zap.Int64("userID", userID), zap.String("modelName", model.Name))
+	return nil
+}
+
+func (s *Service) SetEditingModel(scope defs.Scope, modelID int64) error {
+	s.mu.Lock()
+	s.editingModels[scope] = modelID
+	s.mu.Unlock()
+
+	s.log.Debug("Установка редактируемой модели", zap.Int64("chatID", scope.ChatID), zap.Int64("userID", scope.UserID), zap.Int64("modelID", modelID))
+	return nil
+}
+
+func (s *Service) GetEditingModel(scope defs.Scope) (int64, error) {
+	s.mu.Lock()
+	modelID, ok := s.editingModels[scope]
+	s.mu.Unlock()
+
+	if !ok {
+		return 0, fmt.Errorf("no editing model: %w", defs.ErrNoModel{})
+	}
+	return modelID, nil
+}
+
+func (s *Service) GetUserLanguage(ctx context.Context, userID int64) (string, error) {
+	return s.storage.GetUserLanguage(ctx, userID)
+}
+
+func (s *Service) SetUserLanguage(ctx context.Context, userID int64, lang string) error {
+	err := s.storage.SetUserLanguage(ctx, userID, lang)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("Язык пользователя изменён", zap.Int64("userID", userID), zap.String("lang", lang))
+	return nil
+}
+
+// GetOutputFormat возвращает формат, в котором пользователь получает
+// озвученный текст. По умолчанию это голосовое сообщение.
+func (s *Service) GetOutputFormat(ctx context.Context, userID int64) (string, error) {
+	format, err := s.storage.GetOutputFormat(ctx, userID)
+	if err != nil {
+		s.log.Error("Ошибка получения формата ответа", zap.Error(err))
+		return "", err
+	}
+	if format == "" {
+		return defs.OutputVoice, nil
+	}
+	return format, nil
+}
+
+func (s *Service) SetOutputFormat(ctx context.Context, userID int64, format string) error {
+	err := s.storage.SetOutputFormat(ctx, userID, format)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("Формат ответа изменён", zap.Int64("userID", userID), zap.String("format", format))
+	return nil
+}
+
+// RecordGeneration сохраняет отправленную озвучку в историю
+// пользователя и удаляет записи старше выбранного срока хранения. На
+// запись ссылаются кнопки под озвучкой, поэтому, если пользователь
+// отключил историю, она сохраняется скрытой и удаляется через
+// defs.HiddenGenerationDays.
+func (s *Service) RecordGeneration(ctx context.Context, g defs.Generation) (int64, error) {
+	days, err := s.storage.GetHistoryDays(ctx, g.UserID)
+	if err != nil {
+		return 0, err
+	}
+	g.Hidden = days == 0
+
+	id, err := s.storage.AddGeneration(ctx, g)
+	if err != nil {
+		return 0, err
+	}
+	s.log.Debug("Озвучка сохранена в историю", zap.Int64("userID", g.UserID), zap.Int64("generationID", id), zap.Bool("hidden", g.Hidden))
+
+	return id, s.storage.DeleteOldGenerations(ctx, g.UserID, max(days, defs.HiddenGenerationDays))
+}
+
+// GetGenerations возвращает страницу истории, начиная с последних
+// записей.
+func (s *Service) GetGenerations(ctx context.Context, userID int64, page int) ([]defs.Generation, error) {
+	days, err := s.storage.GetHistoryDays(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.storage.GetGenerations(ctx, userID, days, page*defs.GenerationsPerPage, defs.GenerationsPerPage)
+}
+
+func (s *Service) CountGenerations(ctx context.Context, userID int64) (int, error) {
+	days, err := s.storage.GetHistoryDays(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return s.storage.CountGenerations(ctx, userID, days)
+}
+
+func (s *Service) GetGeneration(ctx context.Context, userID int64, generationID int64) (defs.Generation, error) {
+	return s.storage.GetGeneration(ctx, userID, generationID)
+}
+
+func (s *Service) GetHistoryDays(ctx context.Context, userID int64) (int, error) {
+	return s.storage.GetHistoryDays(ctx, userID)
+}
+
+// SetHistoryDays меняет срок хранения истории и сразу удаляет записи,
+// которые в него не попадают.
+func (s *Service) SetHistoryDays(ctx context.Context, userID int64, days int) error {
+	err := s.storage.SetHistoryDays(ctx, userID, days)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("Срок хранения истории изменён", zap.Int64("userID", userID), zap.Int("days", days))
+	return s.storage.DeleteOldGenerations(ctx, userID, days)
+}
+
+func (s *Service) GetChatSettings(ctx context.Context, chatID int64) (defs.ChatSettings, error) {
+	return s.storage.GetChatSettings(ctx, chatID)
+}
+
+func (s *Service) SetChatAdminOnly(ctx context.Context, chatID int64, adminOnly bool) error {
+	err := s.storage.SetChatAdminOnly(ctx, chatID, adminOnly)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("Изменена настройка чата admin_only", zap.Int64("chatID", chatID), zap.Bool("adminOnly", adminOnly))
+	return nil
+}
+
+// SetChatModel делает модель пользователя общей моделью чата. Если
+// modelID равен 0, участники снова используют свои активные модели.
+func (s *Service) SetChatModel(ctx context.Context, chatID int64, userID int64, modelID int64) error {
+	if modelID != 0 {
+		if _, err := s.GetModel(ctx, userID, modelID); err != nil {
+			return err
+		}
+	}
+
+	err := s.storage.SetChatModel(ctx, chatID, modelID)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("Изменена модель чата", zap.Int64("chatID", chatID), zap.Int64("userID", userID), zap.Int64("modelID", modelID))
+	return nil
+}
+
+// CreateAudiobook сохраняет книгу для озвучки моделью model. Главы
+// нумеруются с нуля в порядке следования в файле.
+func (s *Service) CreateAudiobook(ctx context.Context, scope defs.Scope, lang string, model defs.Model, b *book.Book) (defs.Audiobook, error) {
+	audiobook := defs.Audiobook{
+		UserID:   scope.UserID,
+		ChatID:   scope.ChatID,
+		Model:    model,
+		Title:    b.Title,
+		Lang:     lang,
+		Status:   defs.AudiobookProcessing,
+		Chapters: len(b.Chapters),
+	}
+
+	chapters := make([]defs.Chapter, 0, len(b.Chapters))
+	for i, chapter := range b.Chapters {
+		chapters = append(chapters, defs.Chapter{Index: i, Title: chapter.Title, Text: chapter.Text})
+	}
+
+	bookID, err := s.storage.CreateAudiobook(ctx, audiobook, chapters, s.instance, defs.AudiobookLease)
+	if err != nil {
+		return defs.Audiobook{}, err
+	}
+	audiobook.ID = bookID
+
+	s.log.Info("Книга поставлена на озвучку", zap.Int64("userID", scope.UserID), zap.Int64("bookID", bookID), zap.Int("chapters", len(chapters)))
+	return audiobook, nil
+}
+
+func (s *Service) GetAudiobook(ctx context.Context, bookID int64) (defs.Audiobook, error) {
+	return s.storage.GetAudiobook(ctx, bookID)
+}
+
+// ClaimAudiobooks закрепляет за этой копией бота незавершённые книги
+// без аренды или с истёкшей арендой. reclaimOwn забирает и книги,
+// закреплённые за ней до перезапуска.
+func (s *Service) ClaimAudiobooks(ctx context.Context, reclaimOwn bool) ([]int64, error) {
+	return s.storage.ClaimAudiobooks(ctx, s.instance, defs.AudiobookLease, reclaimOwn)
+}
+
+// ClaimAudiobook продлевает аренду книги. false — книгу озвучивает
+// другая копия бота.
+func (s *Service) ClaimAudiobook(ctx context.Context, bookID int64) (bool, error) {
+	return s.storage.ClaimAudiobook(ctx, bookID, s.instance, defs.AudiobookLease)
+}
+
+// ReleaseAudiobooks снимает аренду с книг этой копии бота при
+// остановке.
+func (s *Service) ReleaseAudiobooks(ctx context.Context) error {
+	released, err := s.storage.ReleaseAudiobooks(ctx, s.instance)
+	if err != nil {
+		return err
+	}
+	if released > 0 {
+		s.log.Info("Аренда книг снята", zap.Int("books", released))
+	}
+	return nil
+}
+
+func (s *Service) SetAudiobookMessage(ctx context.Context, bookID int64, messageID int) error {
+	return s.storage.SetAudiobookMessage(ctx, bookID, messageID)
+}
+
+func (s *Service) SetAudiobookStatus(ctx context.Context, bookID int64, status string) error {
+	err := s.storage.SetAudiobookStatus(ctx, bookID, status)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("Изменён статус книги", zap.Int64("bookID", bookID), zap.String("status", status))
+	return nil
+}
+
+func (s *Service) GetNextChapter(ctx context.Context, bookID int64) (defs.Chapter, bool, error) {
+	return s.storage.GetNextChapter(ctx, bookID)
+}
+
+func (s *Service) MarkChapterDone(ctx context.Context, bookID int64, index int) error {
+	return s.storage.MarkChapterDone(ctx, bookID, index)
+}
+
+// SynthesizeChapter озвучивает главу по фрагментам и собирает их в
+// один MP3. Фрагменты копятся во временном файле, чтобы длинная глава
+// не держалась в памяти. progress вызывается после каждого фрагмента.
+func (s *Service) SynthesizeChapter(ctx context.Context, audiobook defs.Audiobook, chapter defs.Chapter, progress func(done, total int)) (_ []byte, _ int, err error) {
+	segments := book.Segments(chapter.Text, defs.BookSegmentLen)
+	if chapter.Title != "" {
+		segments = append([]string{chapter.Title}, segments...)
+	}
+
+	ctx, span := tracer.Start(ctx, "service.SynthesizeChapter", trace.WithAttributes(
+		attribute.Int64("book.id", audiobook.ID),
+		attribute.Int("book.chapter", chapter.Index),
+		attribute.Int("book.segments", len(segments)),
+		attribute.Int64("tts.model_id", audiobook.Model.ID),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	pcm, err := os.CreateTemp("", "chapter-*.pcm")
+	if err != nil {
+		return nil, 0, fmt.Errorf("ошибка создания временного файла: %w", err)
+	}
+	defer os.Remove(pcm.Name())
+	defer pcm.Close()
+
+	for i, segment := range segments {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+
+		res, err := s.SendAudioWithModel(ctx, audiobook.UserID, audiobook.Model, segment, defs.NormalSpeed)
+		if err != nil {
+			return nil, 0, err
+		}
+		if res.GetStatus() != "OK" {
+			return nil, 0, fmt.Errorf("AudioProcessor вернул статус %q", res.GetStatus())
+		}
+
+		if err := audio.DecodePCM(ctx, res.GetResult().GetProcessedAudio(), pcm); err != nil {
+			return nil, 0, err
+		}
+		if err := audio.Silence(pcm, defs.BookSegmentPause); err != nil {
+			return nil, 0, fmt.Errorf("ошибка записи временного файла: %w", err)
+		}
+		progress(i+1, len(segments))
+	}
+
+	size, err := pcm.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, 0, fmt.Errorf("ошибка чтения временного файла: %w", err)
+	}
+	if _, err := pcm.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, fmt.Errorf("ошибка чтения временного файла: %w", err)
+	}
+
+	mp3, err := audio.EncodeMP3(ctx, pcm, audio.Tags{
+		Title:  chapter.Title,
+		Album:  audiobook.Title,
+		Artist: audiobook.Model.Name,
+		Track:  chapter.Index + 1,
+	})
+	if err != nil {
+		s.log.Error("Ошибка кодирования главы", zap.Int64("bookID", audiobook.ID), zap.Int("index", chapter.Index), zap.Error(err))
+		return nil, 0, err
+	}
+
+	duration := audio.PCMDuration(size)
+	observeAudio(audiobook.Model, duration)
+
+	s.log.Info("Глава озвучена", zap.Int64("bookID", audiobook.ID), zap.Int("index", chapter.Index), zap.Int("segments", len(segments)))
+	return mp3, int(duration.Seconds() + 0.5), nil
+}
+
+// speechKey — адрес озвучки в кэше. Время изменения образца входит в
+// ключ, поэтому после изменения образца старые записи не находятся,
+// даже если их не успели удалить.
+func speechKey(model defs.Model, text string, speed float64) (string, error) {
+	info, err := os.Stat(modelFilePath(model.UserID, model.Name))
+	if err != nil {
+		return "", fmt.Errorf("ошибка чтения файла модели: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d\x00%d\x00%g\x00%s", model.ID, info.ModTime().UnixNano(), speed, text)))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// writeTempFile записывает data во временный файл в dir и возвращает
+// его путь.
+func writeTempFile(dir string, data []byte) (string, error) {
+	f, err := os.CreateTemp(dir, ".upload-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		// Файл модели читает и AudioProcessor.
+		err = f.Chmod(0644)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func modelFilePath(userID int64, modelName string) string {
+	return filepath.Join("voices", strconv.FormatInt(userID, 10), modelName+".ogg")
 }
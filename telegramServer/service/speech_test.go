@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kursach/defs"
+
+	"go.uber.org/zap"
+)
+
+// withModelFile создаёт файл образца model во временном рабочем
+// каталоге теста.
+func withModelFile(t *testing.T, model defs.Model) string {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	path := modelFilePath(model.UserID, model.Name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("ogg"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSpeechKey(t *testing.T) {
+	model := defs.Model{ID: 1, UserID: 10, Name: "голос"}
+	path := withModelFile(t, model)
+
+	key := func(model defs.Model, text string, speed float64) string {
+		t.Helper()
+		k, err := speechKey(model, text, speed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+
+	base := key(model, "привет", 1)
+	if key(model, "привет", 1) != base {
+		t.Fatal("ключ одного и того же запроса меняется")
+	}
+	if key(model, "пока", 1) == base {
+		t.Error("ключ не зависит от текста")
+	}
+	if key(model, "привет", 1.25) == base {
+		t.Error("ключ не зависит от темпа")
+	}
+	other := model
+	other.ID = 2
+	if key(other, "привет", 1) == base {
+		t.Error("ключ не зависит от модели")
+	}
+
+	// Новый образец с тем же именем даёт новый ключ.
+	mtime := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if key(model, "привет", 1) == base {
+		t.Error("ключ не зависит от времени изменения образца")
+	}
+
+	if _, err := speechKey(defs.Model{ID: 3, UserID: 10, Name: "нет"}, "привет", 1); err == nil {
+		t.Error("ключ построен для модели без файла")
+	}
+}
+
+func TestSpeechCache(t *testing.T) {
+	model := defs.Model{ID: 1, UserID: 10, Name: "голос"}
+	withModelFile(t, model)
+	// Без клиента AudioProcessor: все ответы должны быть из кэша.
+	s := NewService(nil, nil, zap.NewNop())
+
+	key, err := speechKey(model, "привет", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	speech := defs.Speech{Key: key, ModelID: model.ID, Speed: 1, Opus: []byte("opus"), Duration: 1}
+	s.speechCache.Put(key, &cachedSpeech{speech: speech, fileIDs: make(map[string]string)})
+
+	got, err := s.Synthesize(context.Background(), model.UserID, model, "привет", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Key != key || string(got.Opus) != "opus" {
+		t.Fatalf("Synthesize вернул %+v, ожидалась запись кэша", got)
+	}
+
+	// file_id запоминается отдельно для каждого формата.
+	s.CacheFileID(key, defs.OutputVoice, "voice-id")
+	if id, ok := s.CachedFileID(key, defs.OutputVoice); !ok || id != "voice-id" {
+		t.Errorf("file_id голосового: %q, %t", id, ok)
+	}
+	if _, ok := s.CachedFileID(key, defs.OutputMP3); ok {
+		t.Error("file_id голосового использован для MP3")
+	}
+	s.CacheFileID(key, defs.OutputMP3, "mp3-id")
+	if id, _ := s.CachedFileID(key, defs.OutputMP3); id != "mp3-id" {
+		t.Errorf("file_id MP3: %q", id)
+	}
+	if _, ok := s.CachedFileID("другой", defs.OutputVoice); ok {
+		t.Error("file_id найден для другого ключа")
+	}
+
+	// После изменения образца записи модели удаляются.
+	s.invalidateSpeech(model.ID)
+	if _, ok := s.CachedFileID(key, defs.OutputVoice); ok {
+		t.Error("file_id остался после сброса кэша модели")
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"kursach/client"
+	pb "kursach/proto"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeProcessor отвечает на Transcribe заданным статусом и запоминает
+// подсказку языка.
+type fakeProcessor struct {
+	pb.UnimplementedAudioProcessorServer
+	status    string
+	languages chan string
+}
+
+func (f *fakeProcessor) Transcribe(_ context.Context, req *pb.TranscribeRequest) (*pb.TranscribeResponse, error) {
+	f.languages <- req.GetLanguage()
+	return &pb.TranscribeResponse{Status: f.status, Text: "привет"}, nil
+}
+
+func newFakeService(t *testing.T, fake *fakeProcessor) *Service {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	pb.RegisterAudioProcessorServer(srv, fake)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	audioClient, err := client.NewAudioProcessorClient(
+		[]client.Endpoint{{Address: "passthrough:///bufnet", MaxConcurrent: 1}},
+		client.Security{},
+		zap.NewNop(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(audioClient.Close)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !audioClient.Backends()[0].Healthy {
+		if time.Now().After(deadline) {
+			t.Fatal("сервер не стал исправным")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return NewService(audioClient, nil, zap.NewNop())
+}
+
+func TestTranscribe(t *testing.T) {
+	fake := &fakeProcessor{status: "OK", languages: make(chan string, 1)}
+	s := newFakeService(t, fake)
+
+	text, err := s.Transcribe(context.Background(), 1, []byte("ogg"), "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "привет" {
+		t.Errorf("text = %q", text)
+	}
+	if language := <-fake.languages; language != "en" {
+		t.Errorf("сервер получил язык %q, want %q", language, "en")
+	}
+}
+
+func TestTranscribeStatusError(t *testing.T) {
+	fake := &fakeProcessor{status: "ERROR: out of memory", languages: make(chan string, 1)}
+	s := newFakeService(t, fake)
+
+	_, err := s.Transcribe(context.Background(), 1, []byte("ogg"), "")
+	if err == nil || !strings.Contains(err.Error(), "out of memory") {
+		t.Fatalf("err = %v, want ошибку со статусом сервера", err)
+	}
+	select {
+	case language := <-fake.languages:
+		if language != "" {
+			t.Errorf("сервер получил язык %q без подсказки", language)
+		}
+	default:
+		t.Error("запрос не дошёл до сервера")
+	}
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+var queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "db_query_duration_seconds",
+	Help:    "Длительность запросов к базе данных по методам Storage.",
+	Buckets: prometheus.ExponentialBuckets(0.0005, 2.5, 12),
+}, []string{"method"})
+
+func init() {
+	prometheus.MustRegister(queryDuration)
+}
+
+// track начинает спан метода Storage и замер его длительности.
+// Возвращённая функция завершает их.
+func track(ctx context.Context, method string) (context.Context, func()) {
+	ctx, span := tracer.Start(ctx, "storage."+method)
+	start := time.Now()
+	return ctx, func() {
+		queryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		span.End()
+	}
+}
@@ -2,9 +2,14 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"go.uber.org/zap"
+	"kursach/defs"
+	"time"
 )
 
 type Storage struct {
@@ -19,7 +24,10 @@ func NewPostgresStorage(db *pgxpool.Pool, logger *zap.Logger) *Storage {
 	}
 }
 
-func (s *Storage) IsUserExists(userId int64) (bool, error) {
+func (s *Storage) IsUserExists(ctx context.Context, userId int64) (bool, error) {
+	ctx, done := track(ctx, "IsUserExists")
+	defer done()
+
 	var exists bool
 
 	query := `
@@ -27,7 +35,7 @@ func (s *Storage) IsUserExists(userId int64) (bool, error) {
 			SELECT 1 FROM users WHERE id = $1
 		)
 	`
-	err := s.db.QueryRow(context.Background(), query, userId).Scan(&exists)
+	err := s.db.QueryRow(ctx, query, userId).Scan(&exists)
 	if err != nil {
 		s.log.Error("Ошибка проверки существования пользователя", zap.Int64("userID", userId), zap.Error(err))
 		return false, fmt.Errorf("ошибка проверки существования пользователя: %w", err)
@@ -37,13 +45,16 @@ func (s *Storage) IsUserExists(userId int64) (bool, error) {
 	return exists, nil
 }
 
-func (s *Storage) AddUser(userId int64) error {
+func (s *Storage) AddUser(ctx context.Context, userId int64) error {
+	ctx, done := track(ctx, "AddUser")
+	defer done()
+
 	query := `
 		INSERT INTO users (id)
 		VALUES ($1)
 		ON CONFLICT (id) DO NOTHING
 	`
-	_, err := s.db.Exec(context.Background(), query, userId)
+	_, err := s.db.Exec(ctx, query, userId)
 	if err != nil {
 		s.log.Error("Ошибка добавления пользователя", zap.Int64("userID", userId), zap.Error(err))
 		return fmt.Errorf("ошибка добавления пользователя: %w", err)
@@ -53,43 +64,51 @@ func (s *Storage) AddUser(userId int64) error {
 	return nil
 }
 
-func (s *Storage) SaveModel(userID int64, modelName string) error {
+func (s *Storage) SaveModel(ctx context.Context, userID int64, modelName string) (int64, error) {
+	ctx, done := track(ctx, "SaveModel")
+	defer done()
+
+	var modelID int64
 	query := `
 		INSERT INTO models (user_id, name)
 		VALUES ($1, $2)
+		RETURNING id
 	`
-	_, err := s.db.Exec(context.Background(), query, userID, modelName)
+	err := s.db.QueryRow(ctx, query, userID, modelName).Scan(&modelID)
 	if err != nil {
 		s.log.Error("Ошибка сохранения модели", zap.Int64("userID", userID), zap.String("modelName", modelName), zap.Error(err))
-		return fmt.Errorf("ошибка сохранения модели: %w", err)
+		return 0, fmt.Errorf("ошибка сохранения модели: %w", err)
 	}
 
 	s.log.Info("Модель успешно сохранена", zap.Int64("userID", userID), zap.String("modelName", modelName))
-	return nil
+	return modelID, nil
 }
 
-func (s *Storage) GetUserModels(userID int64) ([]string, error) {
+func (s *Storage) GetUserModels(ctx context.Context, userID int64) ([]defs.Model, error) {
+	ctx, done := track(ctx, "GetUserModels")
+	defer done()
+
 	query := `
-		SELECT name
+		SELECT id, user_id, name
 		FROM models
 		WHERE user_id = $1
-		ORDER BY created_at
+		ORDER BY created_at, id
 	`
-	rows, err := s.db.Query(context.Background(), query, userID)
+	rows, err := s.db.Query(ctx, query, userID)
 	if err != nil {
 		s.log.Error("Ошибка получения списка моделей", zap.Int64("userID", userID), zap.Error(err))
 		return nil, fmt.Errorf("ошибка получения моделей: %w", err)
 	}
 	defer rows.Close()
 
-	var models []string
+	var models []defs.Model
 	for rows.Next() {
-		var name string
-		if err := rows.Scan(&name); err != nil {
+		var model defs.Model
+		if err := rows.Scan(&model.ID, &model.UserID, &model.Name); err != nil {
 			s.log.Error("Ошибка чтения модели из строки", zap.Int64("userID", userID), zap.Error(err))
 			return nil, fmt.Errorf("ошибка чтения модели: %w", err)
 		}
-		models = append(models, name)
+		models = append(models, model)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -101,12 +120,37 @@ func (s *Storage) GetUserModels(userID int64) ([]string, error) {
 	return models, nil
 }
 
-func (s *Storage) CountModels(userID int64) (int, error) {
+func (s *Storage) GetModel(ctx context.Context, userID int64, modelID int64) (defs.Model, error) {
+	ctx, done := track(ctx, "GetModel")
+	defer done()
+
+	var model defs.Model
+	query := `
+		SELECT id, user_id, name
+		FROM models
+		WHERE user_id = $1 AND id = $2
+	`
+	err := s.db.QueryRow(ctx, query, userID, modelID).Scan(&model.ID, &model.UserID, &model.Name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return defs.Model{}, fmt.Errorf("модель %d не найдена: %w", modelID, defs.ErrNoModel{})
+	}
+	if err != nil {
+		s.log.Error("Ошибка получения модели", zap.Int64("userID", userID), zap.Int64("modelID", modelID), zap.Error(err))
+		return defs.Model{}, fmt.Errorf("ошибка получения модели: %w", err)
+	}
+
+	return model, nil
+}
+
+func (s *Storage) CountModels(ctx context.Context, userID int64) (int, error) {
+	ctx, done := track(ctx, "CountModels")
+	defer done()
+
 	var count int
 	query := `
 		SELECT COUNT(*) FROM models WHERE user_id = $1
 	`
-	err := s.db.QueryRow(context.Background(), query, userID).Scan(&count)
+	err := s.db.QueryRow(ctx, query, userID).Scan(&count)
 	if err != nil {
 		s.log.Error("Ошибка подсчёта моделей пользователя", zap.Int64("userID", userID), zap.Error(err))
 		return 0, err
@@ -116,14 +160,643 @@ func (s *Storage) CountModels(userID int64) (int, error) {
 	return count, nil
 }
 
-func (s *Storage) DeleteModel(userID int64, modelName string) error {
+func (s *Storage) RenameModel(ctx context.Context, userID int64, modelID int64, newName string) error {
+	ctx, done := track(ctx, "RenameModel")
+	defer done()
+
+	query := `
+		UPDATE models
+		SET name = $3
+		WHERE user_id = $1 AND id = $2
+	`
+	tag, err := s.db.Exec(ctx, query, userID, modelID, newName)
+	if err != nil {
+		s.log.Error("Ошибка переименования модели", zap.Int64("userID", userID), zap.Int64("modelID", modelID), zap.Error(err))
+		return fmt.Errorf("ошибка переименования модели: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("модель %d не найдена: %w", modelID, defs.ErrNoModel{})
+	}
+
+	s.log.Info("Модель переименована", zap.Int64("userID", userID), zap.Int64("modelID", modelID), zap.String("newName", newName))
+	return nil
+}
+
+func (s *Storage) DeleteModel(ctx context.Context, userID int64, modelID int64) error {
+	ctx, done := track(ctx, "DeleteModel")
+	defer done()
+
 	query := `
 		DELETE FROM models
-		WHERE user_id = $1 AND name = $2
+		WHERE user_id = $1 AND id = $2
 	`
-	_, err := s.db.Exec(context.Background(), query, userID, modelName)
+	_, err := s.db.Exec(ctx, query, userID, modelID)
 	if err != nil {
 		return fmt.Errorf("ошибка удаления модели: %w", err)
 	}
 	return nil
 }
+
+func (s *Storage) SetActiveModel(ctx context.Context, userID int64, modelID int64) error {
+	ctx, done := track(ctx, "SetActiveModel")
+	defer done()
+
+	query := `
+		INSERT INTO users (id, active_model_id)
+		VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET active_model_id = EXCLUDED.active_model_id
+	`
+	_, err := s.db.Exec(ctx, query, userID, modelID)
+	if err != nil {
+		s.log.Error("Ошибка сохранения активной модели", zap.Int64("userID", userID), zap.Int64("modelID", modelID), zap.Error(err))
+		return fmt.Errorf("ошибка сохранения активной модели: %w", err)
+	}
+
+	s.log.Debug("Активная модель сохранена", zap.Int64("userID", userID), zap.Int64("modelID", modelID))
+	return nil
+}
+
+func (s *Storage) GetActiveModel(ctx context.Context, userID int64) (defs.Model, error) {
+	ctx, done := track(ctx, "GetActiveModel")
+	defer done()
+
+	var model defs.Model
+	query := `
+		SELECT m.id, m.user_id, m.name
+		FROM users u
+		JOIN models m ON m.id = u.active_model_id
+		WHERE u.id = $1
+	`
+	err := s.db.QueryRow(ctx, query, userID).Scan(&model.ID, &model.UserID, &model.Name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return defs.Model{}, fmt.Errorf("активная модель не выбрана: %w", defs.ErrNoModel{})
+	}
+	if err != nil {
+		s.log.Error("Ошибка получения активной модели", zap.Int64("userID", userID), zap.Error(err))
+		return defs.Model{}, fmt.Errorf("ошибка получения активной модели: %w", err)
+	}
+
+	return model, nil
+}
+
+func (s *Storage) SetUserLanguage(ctx context.Context, userID int64, lang string) error {
+	ctx, done := track(ctx, "SetUserLanguage")
+	defer done()
+
+	query := `
+		INSERT INTO users (id, language)
+		VALUES ($1, NULLIF($2, ''))
+		ON CONFLICT (id) DO UPDATE SET language = EXCLUDED.language
+	`
+	_, err := s.db.Exec(ctx, query, userID, lang)
+	if err != nil {
+		s.log.Error("Ошибка сохранения языка пользователя", zap.Int64("userID", userID), zap.String("lang", lang), zap.Error(err))
+		return fmt.Errorf("ошибка сохранения языка пользователя: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) GetUserLanguage(ctx context.Context, userID int64) (string, error) {
+	ctx, done := track(ctx, "GetUserLanguage")
+	defer done()
+
+	var lang string
+	query := `
+		SELECT COALESCE(language, '')
+		FROM users
+		WHERE id = $1
+	`
+	err := s.db.QueryRow(ctx, query, userID).Scan(&lang)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("ошибка получения языка пользователя: %w", err)
+	}
+	return lang, nil
+}
+
+func (s *Storage) SetOutputFormat(ctx context.Context, userID int64, format string) error {
+	ctx, done := track(ctx, "SetOutputFormat")
+	defer done()
+
+	query := `
+		INSERT INTO users (id, output_format)
+		VALUES ($1, NULLIF($2, ''))
+		ON CONFLICT (id) DO UPDATE SET output_format = EXCLUDED.output_format
+	`
+	_, err := s.db.Exec(ctx, query, userID, format)
+	if err != nil {
+		s.log.Error("Ошибка сохранения формата ответа", zap.Int64("userID", userID), zap.String("format", format), zap.Error(err))
+		return fmt.Errorf("ошибка сохранения формата ответа: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) GetOutputFormat(ctx context.Context, userID int64) (string, error) {
+	ctx, done := track(ctx, "GetOutputFormat")
+	defer done()
+
+	var format string
+	query := `
+		SELECT COALESCE(output_format, '')
+		FROM users
+		WHERE id = $1
+	`
+	err := s.db.QueryRow(ctx, query, userID).Scan(&format)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("ошибка получения формата ответа: %w", err)
+	}
+	return format, nil
+}
+
+func (s *Storage) GetChatSettings(ctx context.Context, chatID int64) (defs.ChatSettings, error) {
+	ctx, done := track(ctx, "GetChatSettings")
+	defer done()
+
+	settings := defs.ChatSettings{ChatID: chatID}
+	var modelID, modelUserID *int64
+	var modelName *string
+
+	query := `
+		SELECT cs.admin_only, m.id, m.user_id, m.name
+		FROM chat_settings cs
+		LEFT JOIN models m ON m.id = cs.model_id
+		WHERE cs.chat_id = $1
+	`
+	err := s.db.QueryRow(ctx, query, chatID).Scan(&settings.AdminOnly, &modelID, &modelUserID, &modelName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return settings, nil
+	}
+	if err != nil {
+		s.log.Error("Ошибка получения настроек чата", zap.Int64("chatID", chatID), zap.Error(err))
+		return defs.ChatSettings{}, fmt.Errorf("ошибка получения настроек чата: %w", err)
+	}
+
+	if modelID != nil {
+		settings.Model = defs.Model{ID: *modelID, UserID: *modelUserID, Name: *modelName}
+	}
+	return settings, nil
+}
+
+func (s *Storage) SetChatAdminOnly(ctx context.Context, chatID int64, adminOnly bool) error {
+	ctx, done := track(ctx, "SetChatAdminOnly")
+	defer done()
+
+	query := `
+		INSERT INTO chat_settings (chat_id, admin_only)
+		VALUES ($1, $2)
+		ON CONFLICT (chat_id) DO UPDATE SET admin_only = EXCLUDED.admin_only
+	`
+	_, err := s.db.Exec(ctx, query, chatID, adminOnly)
+	if err != nil {
+		s.log.Error("Ошибка сохранения настроек чата", zap.Int64("chatID", chatID), zap.Error(err))
+		return fmt.Errorf("ошибка сохранения настроек чата: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) SetChatModel(ctx context.Context, chatID int64, modelID int64) error {
+	ctx, done := track(ctx, "SetChatModel")
+	defer done()
+
+	query := `
+		INSERT INTO chat_settings (chat_id, model_id)
+		VALUES ($1, NULLIF($2, 0))
+		ON CONFLICT (chat_id) DO UPDATE SET model_id = EXCLUDED.model_id
+	`
+	_, err := s.db.Exec(ctx, query, chatID, modelID)
+	if err != nil {
+		s.log.Error("Ошибка сохранения модели чата", zap.Int64("chatID", chatID), zap.Int64("modelID", modelID), zap.Error(err))
+		return fmt.Errorf("ошибка сохранения модели чата: %w", err)
+	}
+	return nil
+}
+
+// CreateAudiobook сохраняет книгу вместе с текстом глав, чтобы озвучку
+// можно было продолжить после перезапуска бота. Книга сразу
+// закрепляется за owner на срок lease.
+func (s *Storage) CreateAudiobook(ctx context.Context, book defs.Audiobook, chapters []defs.Chapter, owner string, lease time.Duration) (int64, error) {
+	ctx, done := track(ctx, "CreateAudiobook")
+	defer done()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.log.Error("Ошибка начала транзакции", zap.Error(err))
+		return 0, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var bookID int64
+	query := `
+		INSERT INTO audiobooks (user_id, chat_id, model_id, title, lang, status, owner, lease_until)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, now() + $8 * interval '1 second')
+		RETURNING id
+	`
+	err = tx.QueryRow(ctx, query, book.UserID, book.ChatID, book.Model.ID, book.Title, book.Lang, book.Status, owner, lease.Seconds()).Scan(&bookID)
+	if err != nil {
+		s.log.Error("Ошибка сохранения книги", zap.Int64("userID", book.UserID), zap.Error(err))
+		return 0, fmt.Errorf("ошибка сохранения книги: %w", err)
+	}
+
+	rows := make([][]any, 0, len(chapters))
+	for _, chapter := range chapters {
+		rows = append(rows, []any{bookID, chapter.Index, chapter.Title, chapter.Text})
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"audiobook_chapters"}, []string{"book_id", "idx", "title", "text"}, pgx.CopyFromRows(rows))
+	if err != nil {
+		s.log.Error("Ошибка сохранения глав книги", zap.Int64("bookID", bookID), zap.Error(err))
+		return 0, fmt.Errorf("ошибка сохранения глав книги: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		s.log.Error("Ошибка фиксации транзакции", zap.Error(err))
+		return 0, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	s.log.Info("Книга сохранена", zap.Int64("bookID", bookID), zap.Int("chapters", len(chapters)))
+	return bookID, nil
+}
+
+func (s *Storage) GetAudiobook(ctx context.Context, bookID int64) (defs.Audiobook, error) {
+	ctx, done := track(ctx, "GetAudiobook")
+	defer done()
+
+	var book defs.Audiobook
+
+	query := `
+		SELECT b.id, b.user_id, b.chat_id, b.title, b.lang, b.status, b.message_id,
+		       m.id, m.user_id, m.name,
+		       (SELECT COUNT(*) FROM audiobook_chapters c WHERE c.book_id = b.id),
+		       (SELECT COUNT(*) FROM audiobook_chapters c WHERE c.book_id = b.id AND c.done)
+		FROM audiobooks b
+		JOIN models m ON m.id = b.model_id
+		WHERE b.id = $1
+	`
+	err := s.db.QueryRow(ctx, query, bookID).Scan(
+		&book.ID, &book.UserID, &book.ChatID, &book.Title, &book.Lang, &book.Status, &book.MessageID,
+		&book.Model.ID, &book.Model.UserID, &book.Model.Name,
+		&book.Chapters, &book.ChaptersDone,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return defs.Audiobook{}, fmt.Errorf("книга %d не найдена: %w", bookID, defs.ErrNoAudiobook{})
+	}
+	if err != nil {
+		s.log.Error("Ошибка получения книги", zap.Int64("bookID", bookID), zap.Error(err))
+		return defs.Audiobook{}, fmt.Errorf("ошибка получения книги: %w", err)
+	}
+	return book, nil
+}
+
+// ClaimAudiobooks закрепляет за owner на срок lease незавершённые
+// книги, которые ни за кем не закреплены или чья аренда истекла, и
+// возвращает их в порядке загрузки. С reclaimOwn забираются и книги,
+// уже закреплённые за owner: так копия бота после перезапуска
+// продолжает свои книги. Книги, которые забирает другая копия
+// одновременно, пропускаются.
+func (s *Storage) ClaimAudiobooks(ctx context.Context, owner string, lease time.Duration, reclaimOwn bool) ([]int64, error) {
+	ctx, done := track(ctx, "ClaimAudiobooks")
+	defer done()
+
+	query := `
+		WITH claimed AS (
+			UPDATE audiobooks
+			SET owner = $2, lease_until = now() + $3 * interval '1 second'
+			WHERE id IN (
+				SELECT id
+				FROM audiobooks
+				WHERE status = $1
+				  AND (owner IS NULL OR lease_until < now() OR ($4 AND owner = $2))
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, created_at
+		)
+		SELECT id FROM claimed
+		ORDER BY created_at, id
+	`
+	rows, err := s.db.Query(ctx, query, defs.AudiobookProcessing, owner, lease.Seconds(), reclaimOwn)
+	if err != nil {
+		s.log.Error("Ошибка получения незавершённых книг", zap.Error(err))
+		return nil, fmt.Errorf("ошибка получения незавершённых книг: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			s.log.Error("Ошибка чтения книги", zap.Error(err))
+			return nil, fmt.Errorf("ошибка чтения книги: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ClaimAudiobook продлевает аренду книги owner. Если книга закреплена
+// за другой копией бота и аренда не истекла, возвращает false.
+func (s *Storage) ClaimAudiobook(ctx context.Context, bookID int64, owner string, lease time.Duration) (bool, error) {
+	ctx, done := track(ctx, "ClaimAudiobook")
+	defer done()
+
+	query := `
+		UPDATE audiobooks
+		SET owner = $2, lease_until = now() + $3 * interval '1 second'
+		WHERE id = $1 AND (owner IS NULL OR owner = $2 OR lease_until < now())
+	`
+	tag, err := s.db.Exec(ctx, query, bookID, owner, lease.Seconds())
+	if err != nil {
+		s.log.Error("Ошибка аренды книги", zap.Int64("bookID", bookID), zap.Error(err))
+		return false, fmt.Errorf("ошибка аренды книги: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ReleaseAudiobooks снимает аренду owner с незавершённых книг, чтобы
+// их сразу подхватили другие копии бота.
+func (s *Storage) ReleaseAudiobooks(ctx context.Context, owner string) (int, error) {
+	ctx, done := track(ctx, "ReleaseAudiobooks")
+	defer done()
+
+	query := `
+		UPDATE audiobooks
+		SET owner = NULL, lease_until = NULL
+		WHERE owner = $1 AND status = $2
+	`
+	tag, err := s.db.Exec(ctx, query, owner, defs.AudiobookProcessing)
+	if err != nil {
+		s.log.Error("Ошибка снятия аренды книг", zap.String("owner", owner), zap.Error(err))
+		return 0, fmt.Errorf("ошибка снятия аренды книг: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func (s *Storage) SetAudiobookMessage(ctx context.Context, bookID int64, messageID int) error {
+	ctx, done := track(ctx, "SetAudiobookMessage")
+	defer done()
+
+	query := `
+		UPDATE audiobooks
+		SET message_id = $2
+		WHERE id = $1
+	`
+	_, err := s.db.Exec(ctx, query, bookID, messageID)
+	if err != nil {
+		s.log.Error("Ошибка сохранения сообщения книги", zap.Int64("bookID", bookID), zap.Error(err))
+		return fmt.Errorf("ошибка сохранения сообщения книги: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) SetAudiobookStatus(ctx context.Context, bookID int64, status string) error {
+	ctx, done := track(ctx, "SetAudiobookStatus")
+	defer done()
+
+	query := `
+		UPDATE audiobooks
+		SET status = $2
+		WHERE id = $1
+	`
+	_, err := s.db.Exec(ctx, query, bookID, status)
+	if err != nil {
+		s.log.Error("Ошибка сохранения статуса книги", zap.Int64("bookID", bookID), zap.String("status", status), zap.Error(err))
+		return fmt.Errorf("ошибка сохранения статуса книги: %w", err)
+	}
+	return nil
+}
+
+// GetNextChapter возвращает первую неозвученную главу книги. Если
+// все главы озвучены, ok равен false.
+func (s *Storage) GetNextChapter(ctx context.Context, bookID int64) (defs.Chapter, bool, error) {
+	ctx, done := track(ctx, "GetNextChapter")
+	defer done()
+
+	chapter := defs.Chapter{BookID: bookID}
+
+	query := `
+		SELECT idx, title, text
+		FROM audiobook_chapters
+		WHERE book_id = $1 AND NOT done
+		ORDER BY idx
+		LIMIT 1
+	`
+	err := s.db.QueryRow(ctx, query, bookID).Scan(&chapter.Index, &chapter.Title, &chapter.Text)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return defs.Chapter{}, false, nil
+	}
+	if err != nil {
+		s.log.Error("Ошибка получения главы книги", zap.Int64("bookID", bookID), zap.Error(err))
+		return defs.Chapter{}, false, fmt.Errorf("ошибка получения главы книги: %w", err)
+	}
+	return chapter, true, nil
+}
+
+func (s *Storage) MarkChapterDone(ctx context.Context, bookID int64, index int) error {
+	ctx, done := track(ctx, "MarkChapterDone")
+	defer done()
+
+	query := `
+		UPDATE audiobook_chapters
+		SET done = TRUE
+		WHERE book_id = $1 AND idx = $2
+	`
+	_, err := s.db.Exec(ctx, query, bookID, index)
+	if err != nil {
+		s.log.Error("Ошибка отметки главы озвученной", zap.Int64("bookID", bookID), zap.Int("index", index), zap.Error(err))
+		return fmt.Errorf("ошибка отметки главы озвученной: %w", err)
+	}
+	return nil
+}
+
+// AddGeneration сохраняет запись истории. Озвучивать может и
+// пользователь без своих моделей, например, участник группы с общей
+// моделью чата, поэтому строка users создаётся в той же транзакции.
+func (s *Storage) AddGeneration(ctx context.Context, g defs.Generation) (int64, error) {
+	ctx, done := track(ctx, "AddGeneration")
+	defer done()
+
+	params, err := json.Marshal(g.Params)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка кодирования параметров озвучки: %w", err)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.log.Error("Ошибка начала транзакции", zap.Error(err))
+		return 0, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `INSERT INTO users (id) VALUES ($1) ON CONFLICT (id) DO NOTHING`, g.UserID)
+	if err != nil {
+		s.log.Error("Ошибка добавления пользователя", zap.Int64("userID", g.UserID), zap.Error(err))
+		return 0, fmt.Errorf("ошибка добавления пользователя: %w", err)
+	}
+
+	var id int64
+	query := `
+		INSERT INTO generations (user_id, model_id, model_name, text, params, duration, file_id, hidden)
+		VALUES ($1, NULLIF($2, 0), $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+	err = tx.QueryRow(ctx, query, g.UserID, g.Model.ID, g.Model.Name, g.Text, params, g.Duration, g.FileID, g.Hidden).Scan(&id)
+	if err != nil {
+		s.log.Error("Ошибка сохранения истории озвучки", zap.Int64("userID", g.UserID), zap.Error(err))
+		return 0, fmt.Errorf("ошибка сохранения истории озвучки: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		s.log.Error("Ошибка фиксации транзакции", zap.Error(err))
+		return 0, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+	return id, nil
+}
+
+// GetGenerations возвращает записи истории за последние days дней,
+// начиная с последних.
+func (s *Storage) GetGenerations(ctx context.Context, userID int64, days int, offset int, limit int) ([]defs.Generation, error) {
+	ctx, done := track(ctx, "GetGenerations")
+	defer done()
+
+	query := `
+		SELECT id, user_id, COALESCE(model_id, 0), model_name, text, params, duration, file_id, created_at
+		FROM generations
+		WHERE user_id = $1 AND NOT hidden AND created_at > now() - make_interval(days => $2)
+		ORDER BY created_at DESC, id DESC
+		OFFSET $3
+		LIMIT $4
+	`
+	rows, err := s.db.Query(ctx, query, userID, days, offset, limit)
+	if err != nil {
+		s.log.Error("Ошибка получения истории озвучки", zap.Int64("userID", userID), zap.Error(err))
+		return nil, fmt.Errorf("ошибка получения истории озвучки: %w", err)
+	}
+	defer rows.Close()
+
+	var generations []defs.Generation
+	for rows.Next() {
+		g, err := scanGeneration(rows)
+		if err != nil {
+			s.log.Error("Ошибка чтения записи истории", zap.Error(err))
+			return nil, err
+		}
+		generations = append(generations, g)
+	}
+	return generations, rows.Err()
+}
+
+func (s *Storage) CountGenerations(ctx context.Context, userID int64, days int) (int, error) {
+	ctx, done := track(ctx, "CountGenerations")
+	defer done()
+
+	var count int
+	query := `
+		SELECT COUNT(*)
+		FROM generations
+		WHERE user_id = $1 AND NOT hidden AND created_at > now() - make_interval(days => $2)
+	`
+	err := s.db.QueryRow(ctx, query, userID, days).Scan(&count)
+	if err != nil {
+		s.log.Error("Ошибка подсчёта истории озвучки", zap.Int64("userID", userID), zap.Error(err))
+		return 0, fmt.Errorf("ошибка подсчёта истории озвучки: %w", err)
+	}
+	return count, nil
+}
+
+func (s *Storage) GetGeneration(ctx context.Context, userID int64, generationID int64) (defs.Generation, error) {
+	ctx, done := track(ctx, "GetGeneration")
+	defer done()
+
+	query := `
+		SELECT id, user_id, COALESCE(model_id, 0), model_name, text, params, duration, file_id, created_at
+		FROM generations
+		WHERE id = $1 AND user_id = $2
+	`
+	g, err := scanGeneration(s.db.QueryRow(ctx, query, generationID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return defs.Generation{}, fmt.Errorf("запись истории %d не найдена: %w", generationID, defs.ErrNoGeneration{})
+	}
+	if err != nil {
+		s.log.Error("Ошибка получения записи истории", zap.Int64("generationID", generationID), zap.Error(err))
+		return defs.Generation{}, err
+	}
+	return g, nil
+}
+
+// DeleteOldGenerations удаляет записи истории пользователя старше
+// days дней, а скрытые записи — старше defs.HiddenGenerationDays. При
+// days = 0 удаляются все записи.
+func (s *Storage) DeleteOldGenerations(ctx context.Context, userID int64, days int) error {
+	ctx, done := track(ctx, "DeleteOldGenerations")
+	defer done()
+
+	query := `
+		DELETE FROM generations
+		WHERE user_id = $1
+		  AND (created_at <= now() - make_interval(days => $2)
+		       OR (hidden AND created_at <= now() - make_interval(days => $3)))
+	`
+	_, err := s.db.Exec(ctx, query, userID, days, defs.HiddenGenerationDays)
+	if err != nil {
+		s.log.Error("Ошибка очистки истории озвучки", zap.Int64("userID", userID), zap.Error(err))
+		return fmt.Errorf("ошибка очистки истории озвучки: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) SetHistoryDays(ctx context.Context, userID int64, days int) error {
+	ctx, done := track(ctx, "SetHistoryDays")
+	defer done()
+
+	query := `
+		INSERT INTO users (id, history_days)
+		VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET history_days = EXCLUDED.history_days
+	`
+	_, err := s.db.Exec(ctx, query, userID, days)
+	if err != nil {
+		s.log.Error("Ошибка сохранения срока хранения истории", zap.Int64("userID", userID), zap.Int("days", days), zap.Error(err))
+		return fmt.Errorf("ошибка сохранения срока хранения истории: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) GetHistoryDays(ctx context.Context, userID int64) (int, error) {
+	ctx, done := track(ctx, "GetHistoryDays")
+	defer done()
+
+	var days int
+	query := `
+		SELECT COALESCE(history_days, $2)
+		FROM users
+		WHERE id = $1
+	`
+	err := s.db.QueryRow(ctx, query, userID, defs.DefaultHistoryDays).Scan(&days)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return defs.DefaultHistoryDays, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения срока хранения истории: %w", err)
+	}
+	return days, nil
+}
+
+func scanGeneration(row pgx.Row) (defs.Generation, error) {
+	var g defs.Generation
+	var params []byte
+	err := row.Scan(&g.ID, &g.UserID, &g.Model.ID, &g.Model.Name, &g.Text, &params, &g.Duration, &g.FileID, &g.CreatedAt)
+	if err != nil {
+		return defs.Generation{}, fmt.Errorf("ошибка чтения записи истории: %w", err)
+	}
+
+	if err := json.Unmarshal(params, &g.Params); err != nil {
+		return defs.Generation{}, fmt.Errorf("ошибка разбора параметров озвучки: %w", err)
+	}
+	if g.Params.Speed == 0 {
+		g.Params.Speed = defs.NormalSpeed
+	}
+	return g, nil
+}
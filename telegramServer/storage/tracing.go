@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+	"time"
+)
+
+var tracer = otel.Tracer("kursach/storage")
+
+// TraceQueries добавляет запросы пула в трейсы методов Storage. В pgx v4
+// нет обработчиков начала и конца запроса, только Logger, который
+// вызывается после выполнения с его длительностью, поэтому спан
+// запроса строится задним числом.
+func TraceQueries(config *pgxpool.Config) {
+	config.ConnConfig.Logger = pgx.LoggerFunc(traceQuery)
+	config.ConnConfig.LogLevel = pgx.LogLevelInfo
+}
+
+func traceQuery(ctx context.Context, _ pgx.LogLevel, msg string, data map[string]any) {
+	elapsed, ok := data["time"].(time.Duration)
+	// Запросы вне трейса, например, проверка готовности, не записываются.
+	if !ok || !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	end := time.Now()
+	attrs := []attribute.KeyValue{semconv.DBSystemPostgreSQL}
+	if sql, ok := data["sql"].(string); ok {
+		attrs = append(attrs, semconv.DBQueryText(strings.Join(strings.Fields(sql), " ")))
+	}
+	if table, ok := data["tableName"].(pgx.Identifier); ok {
+		attrs = append(attrs, semconv.DBCollectionName(table.Sanitize()))
+	}
+	if rows, ok := data["rowCount"].(int); ok {
+		attrs = append(attrs, attribute.Int("db.rows", rows))
+	}
+
+	_, span := tracer.Start(ctx, "pgx."+msg,
+		trace.WithTimestamp(end.Add(-elapsed)),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	if err, ok := data["err"].(error); ok {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+}
@@ -0,0 +1,94 @@
+// Package tracing настраивает OpenTelemetry: трейс обновления Telegram
+// проходит через обработчик, сервис, запросы к базе, ffmpeg и запросы к
+// AudioProcessor, куда контекст трейса передаётся в заголовке
+// traceparent.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Экспортёры спанов.
+const (
+	// ExporterNone отключает трейсинг.
+	ExporterNone = "none"
+	// ExporterStdout печатает спаны в stdout, удобно при отладке.
+	ExporterStdout = "stdout"
+	// ExporterOTLP отправляет спаны коллектору по OTLP/gRPC. Адрес и
+	// параметры задаются стандартными переменными окружения
+	// OTEL_EXPORTER_OTLP_*, по умолчанию localhost:4317.
+	ExporterOTLP = "otlp"
+)
+
+type Options struct {
+	Exporter    string
+	ServiceName string
+	// SampleRatio — доля записываемых трейсов. Решение родителя, например,
+	// пришедшее в traceparent, имеет приоритет.
+	SampleRatio float64
+}
+
+// Setup устанавливает глобальные TracerProvider и пропагатор W3C Trace
+// Context. Возвращённая функция отправляет накопленные спаны и
+// останавливает экспортёр.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch opts.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		exporter, err = otlptracegrpc.New(ctx)
+	default:
+		return nil, fmt.Errorf("неизвестный экспортёр трейсов %q", opts.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания экспортёра трейсов: %w", err)
+	}
+
+	// OTEL_SERVICE_NAME и OTEL_RESOURCE_ATTRIBUTES переопределяют имя
+	// сервиса.
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(opts.ServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка описания сервиса для трейсов: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// End завершает span, отмечая его ошибкой, если err не nil. Удобно
+// вызывать через defer с именованным результатом err.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}